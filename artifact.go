@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Artifact — файл для загрузки в Nexus. Это может быть локальный файл из директории
+// импорта или поток, который читается прямо из другого репозитория при миграции.
+type Artifact struct {
+	// Path — путь относительно корня репозитория в раскладке экспорта, всегда через "/".
+	Path string
+	// Size — размер в байтах или -1, если он неизвестен.
+	Size int64
+	// LocalPath — путь к файлу на диске; пуст, если артефакт читается из сети.
+	LocalPath string
+
+	open func(ctx context.Context) (io.ReadCloser, error)
+}
+
+// Name возвращает имя файла артефакта.
+func (a Artifact) Name() string {
+	return path.Base(a.Path)
+}
+
+// Open открывает содержимое артефакта. Каждый вызов начинает чтение с начала,
+// поэтому повтор загрузки после временной ошибки отправляет файл целиком.
+func (a Artifact) Open(ctx context.Context) (io.ReadCloser, error) {
+	return a.open(ctx)
+}
+
+// localArtifact описывает файл filePath внутри директории импорта importDir.
+func localArtifact(filePath, importDir string) (Artifact, error) {
+	relativePath, err := filepath.Rel(importDir, filePath)
+	if err != nil || relativePath == "." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) || relativePath == ".." {
+		return Artifact{}, fmt.Errorf("file path does not start with '%s/': %s", importDir, filePath)
+	}
+
+	size := int64(-1)
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+
+	return Artifact{
+		// Используем filepath.ToSlash для корректной работы на Windows
+		Path:      filepath.ToSlash(relativePath),
+		Size:      size,
+		LocalPath: filePath,
+		open: func(ctx context.Context) (io.ReadCloser, error) {
+			file, err := os.Open(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open file: %w", err)
+			}
+			return file, nil
+		},
+	}, nil
+}
+
+// remoteArtifact описывает ассет другого репозитория Nexus. Содержимое скачивается
+// при каждом Open и сверяется с контрольной суммой из поиска по мере чтения.
+func remoteArtifact(task downloadTask, auth Auth) Artifact {
+	size := task.Size
+	if size <= 0 {
+		size = -1
+	}
+	return Artifact{
+		Path: task.FilePath,
+		Size: size,
+		open: func(ctx context.Context) (io.ReadCloser, error) {
+			resp, err := executeNexusRequest(ctx, "GET", task.URL, "", nil, auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to download source asset: %w", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, statusError("failed to download source asset", resp)
+			}
+			if verifier := newChecksumVerifier(task.Checksum); verifier != nil {
+				return &verifyingReader{ReadCloser: resp.Body, verifier: verifier, path: task.FilePath}, nil
+			}
+			return resp.Body, nil
+		},
+	}
+}
+
+// verifyingReader считает хеш по мере чтения и на EOF возвращает ошибку,
+// если содержимое не совпало с контрольной суммой. Так испорченный поток
+// обрывает загрузку, а не попадает в целевой репозиторий.
+type verifyingReader struct {
+	io.ReadCloser
+	verifier *checksumVerifier
+	path     string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.verifier.Write(p[:n])
+	if err == io.EOF {
+		if verifyErr := r.verifier.Verify(); verifyErr != nil {
+			return n, fmt.Errorf("%s: %w", r.path, verifyErr)
+		}
+	}
+	return n, err
+}
+
+// artifactBody возвращает bodySource, который открывает артефакт заново для каждой попытки.
+func artifactBody(ctx context.Context, artifact Artifact) bodySource {
+	return func() (io.ReadCloser, int64, error) {
+		body, err := artifact.Open(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		return countedBody(ctx, body), artifact.Size, nil
+	}
+}
+
+// countedBody считает для отчета байты файла, отправленные в очередной попытке.
+func countedBody(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	stats := requestStatsFrom(ctx)
+	if stats == nil {
+		return body
+	}
+	// Каждая попытка отправляет файл заново, считаем только последнюю.
+	stats.setBytes(0)
+	return &countingReadCloser{ReadCloser: body, stats: stats}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Auth добавляет учетные данные к запросу в Nexus. nil означает анонимный доступ.
+type Auth interface {
+	Apply(req *http.Request)
+}
+
+// BasicAuth — имя пользователя и пароль. Токены пользователя Nexus (name code и
+// pass code) передаются так же, вместо имени и пароля.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Apply(req *http.Request) {
+	req.SetBasicAuth(a.Username, a.Password)
+}
+
+// BearerAuth — токен для экземпляров за SSO reverse proxy.
+type BearerAuth struct {
+	Token string
+}
+
+func (a BearerAuth) Apply(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+}
+
+// AuthOptions — источники учетных данных для одного экземпляра Nexus.
+// Используется первый заданный в порядке полей: bearer-токен, токен пользователя,
+// имя и пароль, credential helper, ~/.netrc.
+type AuthOptions struct {
+	BearerToken      string
+	UserToken        string // "<name code>:<pass code>"
+	Username         string
+	Password         string
+	CredentialHelper string // команда, которая печатает учетные данные в формате Docker credential helper
+	NetrcFile        string // пусто — $NETRC или ~/.netrc
+}
+
+// resolveAuth выбирает способ аутентификации для serverURL. Если не задано ничего,
+// возвращает nil: запросы пойдут без авторизации, как и раньше.
+func resolveAuth(ctx context.Context, serverURL string, opts AuthOptions) (Auth, error) {
+	switch {
+	case opts.BearerToken != "":
+		return BearerAuth{Token: opts.BearerToken}, nil
+	case opts.UserToken != "":
+		nameCode, passCode, ok := strings.Cut(opts.UserToken, ":")
+		if !ok || nameCode == "" || passCode == "" {
+			return nil, fmt.Errorf("user token must look like <name code>:<pass code>")
+		}
+		return BasicAuth{Username: nameCode, Password: passCode}, nil
+	case opts.Username != "" && opts.Password != "":
+		return BasicAuth{Username: opts.Username, Password: opts.Password}, nil
+	case opts.CredentialHelper != "":
+		return helperAuth(ctx, opts.CredentialHelper, serverURL)
+	}
+	return netrcAuth(opts.NetrcFile, serverURL)
+}
+
+// helperCredentials — ответ credential helper, как у Docker: {"Username": ..., "Secret": ...}.
+type helperCredentials struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+// helperTokenUsername — имя пользователя, которым Docker credential helper
+// обозначает, что Secret — токен, а не пароль.
+const helperTokenUsername = "<token>"
+
+// helperAuth запускает "<command> get", передает serverURL на stdin и читает JSON
+// с учетными данными из stdout. Секрет не попадает ни в argv, ни в окружение.
+func helperAuth(ctx context.Context, command, serverURL string) (Auth, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("credential helper command is empty")
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], "get")...)
+	cmd.Stdin = strings.NewReader(serverURL)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var creds helperCredentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return nil, fmt.Errorf("credential helper %s returned invalid JSON: %w", args[0], err)
+	}
+	if creds.Secret == "" {
+		return nil, fmt.Errorf("credential helper %s returned no secret for %s", args[0], serverURL)
+	}
+	if creds.Username == "" || creds.Username == helperTokenUsername {
+		return BearerAuth{Token: creds.Secret}, nil
+	}
+	return BasicAuth{Username: creds.Username, Password: creds.Secret}, nil
+}
+
+// netrcAuth ищет в .netrc запись machine для хоста serverURL, а если ее нет — default.
+// Отсутствие файла или записи не ошибка: тогда возвращается nil.
+func netrcAuth(path, serverURL string) (Auth, error) {
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netrc: %w", err)
+	}
+	defer file.Close()
+
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL %q: %w", serverURL, err)
+	}
+	host := parsed.Hostname()
+
+	// Формат .netrc — поток токенов: machine <host> login <name> password <secret>, default ...
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanWords)
+	var tokens []string
+	for scanner.Scan() {
+		tokens = append(tokens, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read netrc: %w", err)
+	}
+
+	var found, fallback *BasicAuth
+	var current *BasicAuth
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(tokens) {
+				i++
+				if tokens[i] == host && found == nil {
+					found = &BasicAuth{}
+					current = found
+				}
+			}
+		case "default":
+			current = nil
+			if fallback == nil {
+				fallback = &BasicAuth{}
+				current = fallback
+			}
+		case "login":
+			if i+1 < len(tokens) {
+				i++
+				if current != nil {
+					current.Username = tokens[i]
+				}
+			}
+		case "password":
+			if i+1 < len(tokens) {
+				i++
+				if current != nil {
+					current.Password = tokens[i]
+				}
+			}
+		case "macdef":
+			// Макросы тянутся до пустой строки, в них учетных данных нет.
+			current = nil
+		}
+	}
+
+	for _, auth := range []*BasicAuth{found, fallback} {
+		if auth != nil && auth.Username != "" && auth.Password != "" {
+			return *auth, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveAuthSources(t *testing.T) {
+	dir := t.TempDir()
+	netrc := filepath.Join(dir, "netrc")
+	content := "machine other.example.com login other password other-secret\n" +
+		"machine nexus.example.com\n  login deployer\n  password netrc-secret\n" +
+		"default login anonymous password guest\n"
+	if err := os.WriteFile(netrc, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		opts AuthOptions
+		want Auth
+	}{
+		{"bearer token wins", "https://nexus.example.com", AuthOptions{BearerToken: "sso", Username: "u", Password: "p"}, BearerAuth{Token: "sso"}},
+		{"user token", "https://nexus.example.com", AuthOptions{UserToken: "nameCode:passCode"}, BasicAuth{Username: "nameCode", Password: "passCode"}},
+		{"username and password", "https://nexus.example.com", AuthOptions{Username: "u", Password: "p", NetrcFile: netrc}, BasicAuth{Username: "u", Password: "p"}},
+		{"netrc machine", "https://nexus.example.com:8443/", AuthOptions{NetrcFile: netrc}, BasicAuth{Username: "deployer", Password: "netrc-secret"}},
+		{"netrc default", "https://unknown.example.com", AuthOptions{NetrcFile: netrc}, BasicAuth{Username: "anonymous", Password: "guest"}},
+		{"nothing configured", "https://nexus.example.com", AuthOptions{NetrcFile: filepath.Join(dir, "missing")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveAuth(context.Background(), tt.url, tt.opts)
+			if err != nil {
+				t.Fatalf("resolveAuth failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := resolveAuth(context.Background(), "https://nexus.example.com", AuthOptions{UserToken: "no-separator"}); err == nil {
+		t.Error("Expected error for malformed user token")
+	}
+}
+
+func TestHelperAuth(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script requires a POSIX shell")
+	}
+
+	// 1. Helper по протоколу Docker: читает URL сервера из stdin и печатает JSON
+	dir := t.TempDir()
+	script := filepath.Join(dir, "nexus-credential-helper")
+	helper := `#!/bin/sh
+[ "$1" = "get" ] || exit 1
+read server
+case "$server" in
+  https://token.example.com) echo '{"Username": "<token>", "Secret": "sso-token"}' ;;
+  *) echo '{"Username": "deployer", "Secret": "helper-secret"}' ;;
+esac
+`
+	if err := os.WriteFile(script, []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. Обычные учетные данные превращаются в Basic, "<token>" — в Bearer
+	got, err := resolveAuth(context.Background(), "https://nexus.example.com", AuthOptions{CredentialHelper: script})
+	if err != nil {
+		t.Fatalf("resolveAuth failed: %v", err)
+	}
+	if got != (BasicAuth{Username: "deployer", Password: "helper-secret"}) {
+		t.Errorf("Unexpected credentials from helper: %#v", got)
+	}
+	got, err = resolveAuth(context.Background(), "https://token.example.com", AuthOptions{CredentialHelper: script})
+	if err != nil {
+		t.Fatalf("resolveAuth failed: %v", err)
+	}
+	if got != (BearerAuth{Token: "sso-token"}) {
+		t.Errorf("Expected bearer token from helper, got %#v", got)
+	}
+
+	// 3. Запрос с BearerAuth несет заголовок Authorization: Bearer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sso-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	resp, err := executeNexusRequest(context.Background(), "GET", server.URL, "", nil, got)
+	if err != nil {
+		t.Fatalf("executeNexusRequest failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected bearer token to be accepted, got %s", resp.Status)
+	}
+}
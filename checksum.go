@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// checksumAlgorithms перечисляет алгоритмы, которые возвращает Nexus,
+// в порядке предпочтения: от самого надежного к самому слабому.
+var checksumAlgorithms = []struct {
+	Name string
+	New  func() hash.Hash
+}{
+	{"sha512", sha512.New},
+	{"sha256", sha256.New},
+	{"sha1", sha1.New},
+	{"md5", md5.New},
+}
+
+// checksumVerifier считает хеш потока и сравнивает его с ожидаемым значением.
+type checksumVerifier struct {
+	Algorithm string
+	Expected  string
+	hash      hash.Hash
+}
+
+// newChecksumVerifier выбирает самый надежный алгоритм из карты checksum ассета.
+// Возвращает nil, если Nexus не прислал ни одной известной контрольной суммы.
+func newChecksumVerifier(checksums map[string]string) *checksumVerifier {
+	for _, algo := range checksumAlgorithms {
+		if expected := checksums[algo.Name]; expected != "" {
+			return &checksumVerifier{
+				Algorithm: algo.Name,
+				Expected:  strings.ToLower(expected),
+				hash:      algo.New(),
+			}
+		}
+	}
+	return nil
+}
+
+func (v *checksumVerifier) Write(p []byte) (int, error) {
+	return v.hash.Write(p)
+}
+
+// Sum возвращает вычисленный хеш в шестнадцатеричном виде.
+func (v *checksumVerifier) Sum() string {
+	return hex.EncodeToString(v.hash.Sum(nil))
+}
+
+// Verify сравнивает вычисленный хеш с ожидаемым.
+func (v *checksumVerifier) Verify() error {
+	if actual := v.Sum(); actual != v.Expected {
+		return fmt.Errorf("checksum mismatch (%s): expected %s, got %s", v.Algorithm, v.Expected, actual)
+	}
+	return nil
+}
+
+// fileMatchesChecksum хеширует локальный файл и сравнивает его с контрольной суммой ассета.
+// Второе значение равно false, если сравнивать не с чем.
+func fileMatchesChecksum(path string, checksums map[string]string) (match bool, known bool, err error) {
+	verifier := newChecksumVerifier(checksums)
+	if verifier == nil {
+		return false, false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, true, err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(verifier, file); err != nil {
+		return false, true, err
+	}
+	return verifier.Verify() == nil, true, nil
+}
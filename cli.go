@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// programName — имя исполняемого файла в справке и скриптах автодополнения.
+const programName = "nexus-operator"
+
+// Коды выхода. Документированы в README и в справке каждой команды.
+const (
+	exitOK          = 0
+	exitFailure     = 1   // ошибка выполнения: сеть, Nexus, файлы
+	exitUsage       = 2   // неверные аргументы командной строки
+	exitDifferences = 3   // diff и verify нашли расхождения
+	exitPartial     = 4   // запуск завершен, но часть файлов не обработана: имеет смысл повторить с -resume
+	exitAuth        = 5   // Nexus отклонил учетные данные или не хватает прав
+	exitNotFound    = 6   // репозиторий или ассет не найден
+	exitConflict    = 7   // ассет уже есть, а повторная публикация запрещена
+	exitInterrupted = 130 // остановка по SIGINT/SIGTERM, как принято в shell
+)
+
+// envFlags — переменные окружения, которые подставляются вместо незаданных флагов.
+var envFlags = map[string]string{
+	"username":            "NEXUS_USERNAME",
+	"password":            "NEXUS_PASSWORD",
+	"target-username":     "NEXUS_TARGET_USERNAME",
+	"target-password":     "NEXUS_TARGET_PASSWORD",
+	"user-token":          "NEXUS_USER_TOKEN",
+	"bearer-token":        "NEXUS_BEARER_TOKEN",
+	"target-user-token":   "NEXUS_TARGET_USER_TOKEN",
+	"target-bearer-token": "NEXUS_TARGET_BEARER_TOKEN",
+	"credential-helper":   "NEXUS_CREDENTIAL_HELPER",
+	"nuget-api-key":       "NEXUS_NUGET_API_KEY",
+	"profile":             "NEXUS_PROFILE",
+}
+
+// cliOptions — значения флагов команды. Каждая команда регистрирует только нужные
+// ей группы флагов, поля остальных остаются нулевыми.
+type cliOptions struct {
+	RepoURL  string
+	RepoName string
+	RepoType string
+
+	Username         string
+	Password         string
+	UserToken        string
+	BearerToken      string
+	CredentialHelper string
+
+	TargetURL         string
+	TargetRepo        string
+	TargetUsername    string
+	TargetPassword    string
+	TargetUserToken   string
+	TargetBearerToken string
+
+	ImportDir       string
+	OnConflict      string
+	MavenComponents bool
+	NugetAPIKey     string
+	LocalDir        string
+	Direction       string
+	Delete          bool
+	DryRun          bool
+	Incremental     bool
+	SimpleIndex     bool
+	Workers         int
+	QueueSize       int
+	Journal         string
+	Resume          bool
+
+	ReportPath   string
+	ReportFormat string
+
+	Retry      RetryPolicy
+	HTTP       HTTPClientConfig
+	ConfigPath string
+	Profile    string
+
+	// Заполняются после разбора флагов и перед запуском команды.
+	Auth       Auth
+	TargetAuth Auth
+	Report     *Report
+}
+
+// flagGroup регистрирует связанные флаги в наборе флагов команды.
+type flagGroup func(fs *flag.FlagSet, o *cliOptions)
+
+// repoTypeMode — насколько команде нужен тип репозитория.
+type repoTypeMode int
+
+const (
+	repoTypeUnused   repoTypeMode = iota
+	repoTypeOptional              // влияет только на раскладку файлов; без него — раскладка raw
+	repoTypeRequired              // нужен загрузчик формата
+)
+
+// command описывает подкоманду: ее флаги, справку и запуск. Из этого же описания
+// строятся скрипты автодополнения.
+type command struct {
+	Name        string
+	Usage       string // аргументы после имени команды в строке "Usage:"
+	Summary     string // одна строка для общего списка команд
+	Description string
+	Examples    []string
+
+	Flags    []flagGroup
+	Required []string // флаги, без которых команда не запускается
+	RepoType repoTypeMode
+	Validate func(o *cliOptions) error
+
+	// Title — название действия в итоговом сообщении ("Export failed: ...").
+	Title string
+	// Silent отключает сообщение "<Title> completed successfully.": команда сама печатает результат.
+	Silent bool
+	// Differences — команда возвращает код 3, если нашла расхождения.
+	Differences bool
+	// Offline — команда не обращается к Nexus: настройки подключения не читаются.
+	Offline bool
+
+	Run func(ctx context.Context, o *cliOptions, args []string) error
+}
+
+// usageError — ошибка в аргументах командной строки, завершает работу с кодом 2.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// findCommand возвращает команду по имени или nil.
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// runCLI разбирает аргументы командной строки (без имени программы), выполняет
+// команду и возвращает код выхода.
+func runCLI(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	if strings.HasPrefix(args[0], "-") {
+		legacy, ok := legacyArgs(args)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: command is missing, flags must follow it: %s <command> [flags]\n\n", programName)
+			printUsage(os.Stderr)
+			return exitUsage
+		}
+		fmt.Fprintf(os.Stderr, "Флаг -action устарел, используйте: %s %s [flags]\n", programName, legacy[0])
+		args = legacy
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	return cmd.execute(args[1:])
+}
+
+// legacyArgs переписывает старую форму "-action=export -repo-url=..." в
+// "export -repo-url=...", чтобы существующие скрипты продолжали работать.
+func legacyArgs(args []string) ([]string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimPrefix(args[i], "-")
+		arg = strings.TrimPrefix(arg, "-")
+		var name string
+		rest := append([]string{}, args[:i]...)
+		switch {
+		case strings.HasPrefix(arg, "action="):
+			name = strings.TrimPrefix(arg, "action=")
+			rest = append(rest, args[i+1:]...)
+		case arg == "action" && i+1 < len(args):
+			name = args[i+1]
+			rest = append(rest, args[i+2:]...)
+		default:
+			continue
+		}
+		return append([]string{name}, rest...), true
+	}
+	return nil, false
+}
+
+// printUsage печатает общую справку: список команд и коды выхода.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\n", programName)
+	fmt.Fprintln(w, "Export, import and migrate artifacts of Sonatype Nexus Repository Manager.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Run '%s help <command>' or '%s <command> -h' for command flags and examples.\n", programName, programName)
+	fmt.Fprintln(w)
+	printExitCodes(w, true)
+}
+
+// printExitCodes печатает коды выхода; код 3 — только для команд, которые ищут расхождения.
+func printExitCodes(w io.Writer, differences bool) {
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintf(w, "  %-4d success\n", exitOK)
+	fmt.Fprintf(w, "  %-4d failure: network, Nexus or file system error\n", exitFailure)
+	fmt.Fprintf(w, "  %-4d invalid command line\n", exitUsage)
+	if differences {
+		fmt.Fprintf(w, "  %-4d differences found (diff, verify)\n", exitDifferences)
+	}
+	fmt.Fprintf(w, "  %-4d partial failure: some files failed, re-run with -resume to retry them\n", exitPartial)
+	fmt.Fprintf(w, "  %-4d authentication failed or permission denied (401, 403)\n", exitAuth)
+	fmt.Fprintf(w, "  %-4d repository or asset not found (404)\n", exitNotFound)
+	fmt.Fprintf(w, "  %-4d conflict: the asset already exists and redeploy is not allowed (409)\n", exitConflict)
+	fmt.Fprintf(w, "  %-4d interrupted by SIGINT/SIGTERM\n", exitInterrupted)
+}
+
+// flagSet создает набор флагов команды, связанный с полями o.
+func (c *command) flagSet(o *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(programName+" "+c.Name, flag.ContinueOnError)
+	for _, group := range c.Flags {
+		group(fs, o)
+	}
+	fs.Usage = func() { c.printHelp(fs.Output(), fs) }
+	return fs
+}
+
+// printHelp печатает справку команды: описание, флаги, примеры и коды выхода.
+func (c *command) printHelp(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: %s %s %s\n\n", programName, c.Name, c.Usage)
+	fmt.Fprintln(w, c.Description)
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Flags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+	if len(c.Examples) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Examples:")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+	fmt.Fprintln(w)
+	printExitCodes(w, c.Differences)
+}
+
+// execute разбирает флаги команды, применяет переменные окружения и профиль,
+// настраивает подключение к Nexus и запускает команду.
+func (c *command) execute(args []string) int {
+	o := &cliOptions{}
+	fs := c.flagSet(o)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		// Ошибку и справку уже напечатал пакет flag.
+		return exitUsage
+	}
+
+	if c.Offline {
+		return c.finish(c.Run(context.Background(), o, fs.Args()), o)
+	}
+	if fs.NArg() > 0 {
+		return c.finish(usageError(fmt.Sprintf("unexpected arguments: %s", strings.Join(fs.Args(), " "))), o)
+	}
+	if err := c.configure(fs, o); err != nil {
+		return c.finish(err, o)
+	}
+
+	// Каждому воркеру по соединению в пуле.
+	o.HTTP.MaxIdleConnsPerHost = defaultHTTPClientConfig.MaxIdleConnsPerHost
+	if o.Workers > 0 {
+		o.HTTP.MaxIdleConnsPerHost = o.Workers
+	}
+	client, err := newHTTPClient(o.HTTP)
+	if err != nil {
+		return c.finish(err, o)
+	}
+	httpClient = client
+	retryPolicy = o.Retry
+
+	ctx, cancel := withSignalCancel()
+	defer cancel()
+
+	if err := c.connect(ctx, fs, o); err != nil {
+		return c.finish(err, o)
+	}
+	if o.ReportPath != "" {
+		o.Report = newReport(c.Name, o.RepoURL, o.RepoName, o.DryRun)
+	}
+	return c.finish(c.Run(ctx, o, fs.Args()), o)
+}
+
+// configure дополняет флаги переменными окружения и профилем из файла настроек
+// и проверяет обязательные флаги.
+func (c *command) configure(fs *flag.FlagSet, o *cliOptions) error {
+	// Приоритет настроек: флаги, затем переменные окружения, затем профиль из файла настроек.
+	// Переменные окружения удобны для CI/CD.
+	skip := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { skip[f.Name] = true })
+	for name, env := range envFlags {
+		if value := os.Getenv(env); value != "" && !skip[name] && fs.Lookup(name) != nil {
+			fs.Set(name, value)
+			skip[name] = true
+		}
+	}
+
+	cfg, err := loadConfig(o.ConfigPath, skip["config"])
+	if err != nil {
+		return err
+	}
+	profile, err := cfg.profile(o.Profile)
+	if err != nil {
+		return err
+	}
+	if err := applyConfig(fs, skip, profile); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, name := range c.Required {
+		if fs.Lookup(name).Value.String() == "" {
+			missing = append(missing, "-"+name)
+		}
+	}
+	if len(missing) > 0 {
+		return usageError(fmt.Sprintf("missing required flags: %s", strings.Join(missing, ", ")))
+	}
+	if fs.Lookup("report-format") != nil && o.ReportFormat != reportJSON && o.ReportFormat != reportJUnit {
+		return usageError(fmt.Sprintf("invalid -report-format %q, use '%s' or '%s'", o.ReportFormat, reportJSON, reportJUnit))
+	}
+	if c.Validate != nil {
+		return c.Validate(o)
+	}
+	return nil
+}
+
+// connect получает учетные данные источника и цели и, если -repo-type не задан,
+// определяет тип репозитория по его формату в Nexus.
+func (c *command) connect(ctx context.Context, fs *flag.FlagSet, o *cliOptions) error {
+	auth, err := resolveAuth(ctx, o.RepoURL, AuthOptions{
+		BearerToken:      o.BearerToken,
+		UserToken:        o.UserToken,
+		Username:         o.Username,
+		Password:         o.Password,
+		CredentialHelper: o.CredentialHelper,
+	})
+	if err != nil {
+		return err
+	}
+	o.Auth = auth
+
+	if fs.Lookup("target-url") != nil {
+		// Перенос и сравнение внутри одного экземпляра: целевой URL и учетные данные берутся от источника.
+		o.TargetAuth = auth
+		noTargetCredentials := o.TargetUsername == "" && o.TargetPassword == "" && o.TargetUserToken == "" && o.TargetBearerToken == ""
+		if o.TargetURL == "" {
+			o.TargetURL = o.RepoURL
+		}
+		if o.TargetURL != o.RepoURL || !noTargetCredentials {
+			o.TargetAuth, err = resolveAuth(ctx, o.TargetURL, AuthOptions{
+				BearerToken:      o.TargetBearerToken,
+				UserToken:        o.TargetUserToken,
+				Username:         o.TargetUsername,
+				Password:         o.TargetPassword,
+				CredentialHelper: o.CredentialHelper,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.RepoType != "" || c.RepoType == repoTypeUnused {
+		return nil
+	}
+	repoType, err := detectRepoType(ctx, o.RepoURL, o.RepoName, o.Auth)
+	switch {
+	case err == nil:
+		o.RepoType = repoType
+	case c.RepoType == repoTypeRequired:
+		return fmt.Errorf("cannot detect repository type, pass -repo-type: %w", err)
+	default:
+		fmt.Fprintf(os.Stderr, "Не удалось определить тип репозитория (%v), используется раскладка raw. Укажите -repo-type.\n", err)
+	}
+	return nil
+}
+
+// finish сохраняет отчет, печатает итог команды и возвращает код выхода. Отчет пишется
+// и для неудачного запуска: CI нужен именно список упавших файлов.
+func (c *command) finish(err error, o *cliOptions) int {
+	var usage usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintf(os.Stderr, "Error: %v\nRun '%s help %s' for usage.\n", err, programName, c.Name)
+		return exitUsage
+	}
+
+	code := exitCodeFor(err)
+	if o.Report != nil {
+		o.Report.Finish(err)
+		if writeErr := o.Report.WriteFile(o.ReportPath, o.ReportFormat); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save report: %v\n", writeErr)
+			if err == nil {
+				code = exitFailure
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, errDifferencesFound):
+		// Расхождения уже напечатаны командой.
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", c.Title, err)
+		printFailedItems(err)
+	case !c.Silent:
+		fmt.Printf("%s completed successfully.\n", c.Title)
+	}
+	return code
+}
+
+// withSignalCancel возвращает контекст, который отменяется по первому SIGINT/SIGTERM.
+// Второй сигнал завершает процесс немедленно.
+func withSignalCancel() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "\nПолучен сигнал остановки: текущие задачи прерываются, новые не запускаются. Повторный сигнал завершит работу немедленно.")
+		cancel()
+		<-signals
+		fmt.Fprintln(os.Stderr, "Принудительное завершение.")
+		os.Exit(exitInterrupted)
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// maxPrintedFailures — сколько упавших файлов перечислять в итоге; полный список есть в -report.
+const maxPrintedFailures = 20
+
+// printFailedItems перечисляет файлы, которые не удалось обработать.
+func printFailedItems(err error) {
+	var partial *PartialFailure
+	if !errors.As(err, &partial) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Failed items:")
+	for i, item := range partial.Failed {
+		if i == maxPrintedFailures {
+			fmt.Fprintf(os.Stderr, "  ... and %d more\n", len(partial.Failed)-maxPrintedFailures)
+			break
+		}
+		fmt.Fprintf(os.Stderr, "  %s\n", item.Path)
+	}
+}
+
+// exitCodeFor возвращает код выхода для ошибки. Частичный сбой, в котором все файлы
+// упали на авторизации, считается ошибкой авторизации: повтор ничего не даст.
+func exitCodeFor(err error) int {
+	var partial *PartialFailure
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, errDifferencesFound):
+		return exitDifferences
+	case errors.As(err, &partial):
+		if partial.onlyAuth() {
+			return exitAuth
+		}
+		return exitPartial
+	case errors.Is(err, ErrAuth):
+		return exitAuth
+	case errors.Is(err, ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, ErrConflict):
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// repoTypes возвращает поддерживаемые значения -repo-type по алфавиту.
+func repoTypes() []string {
+	var types []string
+	for name := range uploaders {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandFlagSets(t *testing.T) {
+	// 1. У каждой команды только свои флаги
+	tests := []struct {
+		command string
+		has     []string
+		lacks   []string
+	}{
+		{"export", []string{"repo-url", "repo-name", "repo-type", "incremental", "queue-size", "journal", "report"}, []string{"import-dir", "target-repo", "local-dir"}},
+		{"import", []string{"import-dir", "dry-run", "resume"}, []string{"incremental", "queue-size", "target-repo"}},
+		{"migrate", []string{"target-url", "target-repo", "target-user-token", "queue-size"}, []string{"import-dir", "local-dir"}},
+		{"verify", []string{"local-dir", "workers"}, []string{"target-repo", "dry-run", "delete"}},
+		{"repos", []string{"repo-url", "bearer-token", "ca-cert"}, []string{"repo-name", "workers", "repo-type"}},
+		{"completion", nil, []string{"repo-url"}},
+	}
+	for _, tt := range tests {
+		fs := findCommand(tt.command).flagSet(&cliOptions{})
+		for _, name := range tt.has {
+			if fs.Lookup(name) == nil {
+				t.Errorf("%s: expected flag -%s", tt.command, name)
+			}
+		}
+		for _, name := range tt.lacks {
+			if fs.Lookup(name) != nil {
+				t.Errorf("%s: unexpected flag -%s", tt.command, name)
+			}
+		}
+	}
+
+	// 2. Export больше не требует -repo-type
+	if required := findCommand("export").Required; !reflect.DeepEqual(required, []string{"repo-url", "repo-name"}) {
+		t.Errorf("Unexpected required flags for export: %v", required)
+	}
+}
+
+func TestRunCLIUsageErrors(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(config, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. Ошибки командной строки завершаются кодом 2, справка — кодом 0
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no command", nil, exitUsage},
+		{"unknown command", []string{"publish"}, exitUsage},
+		{"unknown flag", []string{"repos", "-import-dir=./x"}, exitUsage},
+		{"missing required flag", []string{"import", "-config=" + config, "-repo-url=https://nexus.example.com", "-repo-name=raw"}, exitUsage},
+		{"diff without other side", []string{"diff", "-config=" + config, "-repo-url=https://nexus.example.com", "-repo-name=raw"}, exitUsage},
+		{"unexpected argument", []string{"list", "-config=" + config, "-repo-url=https://nexus.example.com", "-repo-name=raw", "extra"}, exitUsage},
+		{"unsupported shell", []string{"completion", "tcsh"}, exitUsage},
+		{"command help", []string{"export", "-h"}, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCLI(tt.args); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLegacyArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-repo-url=u", "-action=export", "-repo-name=r"}, []string{"export", "-repo-url=u", "-repo-name=r"}},
+		{[]string{"--action", "import", "-import-dir=d"}, []string{"import", "-import-dir=d"}},
+	}
+	for _, tt := range tests {
+		got, ok := legacyArgs(tt.args)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("legacyArgs(%v) = %v, %v; expected %v", tt.args, got, ok, tt.want)
+		}
+	}
+	if _, ok := legacyArgs([]string{"-repo-url=u"}); ok {
+		t.Error("Expected no command without -action")
+	}
+}
+
+func TestRepositories(t *testing.T) {
+	// 1. Сервер отдает список репозиториев
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service/rest/v1/repositories" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]Repository{
+			{Name: "maven-releases", Format: "maven2", Type: "hosted", URL: "http://nexus/repository/maven-releases"},
+			{Name: "npm-proxy", Format: "npm", Type: "proxy", URL: "http://nexus/repository/npm-proxy"},
+		})
+	}))
+	defer server.Close()
+
+	// 2. Формат maven2 превращается в -repo-type=maven
+	repoType, err := detectRepoType(context.Background(), server.URL, "maven-releases", nil)
+	if err != nil || repoType != "maven" {
+		t.Errorf("Expected repo type maven, got %q (%v)", repoType, err)
+	}
+	if _, err := detectRepoType(context.Background(), server.URL, "missing", nil); err == nil {
+		t.Error("Expected error for unknown repository")
+	}
+
+	// 3. repos печатает таблицу
+	var out bytes.Buffer
+	if err := ListRepositories(context.Background(), server.URL, nil, &out); err != nil {
+		t.Fatalf("ListRepositories failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "npm-proxy") || !strings.Contains(out.String(), "maven ") {
+		t.Errorf("Unexpected repository table:\n%s", out.String())
+	}
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range completionShells {
+		var out bytes.Buffer
+		if err := writeCompletion(&out, shell); err != nil {
+			t.Fatalf("%s: writeCompletion failed: %v", shell, err)
+		}
+		script := out.String()
+		// Скрипт строится из описания команд: в нем есть все команды, их флаги и значения перечислений.
+		for _, cmd := range commands() {
+			if !strings.Contains(script, cmd.Name) {
+				t.Errorf("%s: command %s is missing", shell, cmd.Name)
+			}
+		}
+		for _, want := range []string{"import-dir", "target-repo", "junit", "from-repo", "nuget"} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s: %q is missing", shell, want)
+			}
+		}
+	}
+}
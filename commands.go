@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --- Группы флагов ---
+
+// connectionFlags — адрес Nexus, учетные данные, сеть, TLS и файл настроек. Есть у всех команд, работающих с Nexus.
+func connectionFlags(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.RepoURL, "repo-url", "", "Base URL of the Nexus repository (e.g., https://nexus.ac.astralinux.ru)")
+	fs.StringVar(&o.Username, "username", "", "Username for Nexus authentication (optional)")
+	fs.StringVar(&o.Password, "password", "", "Password for Nexus authentication (optional)")
+	fs.StringVar(&o.UserToken, "user-token", "", "Nexus user token as <name code>:<pass code>, used instead of -username/-password")
+	fs.StringVar(&o.BearerToken, "bearer-token", "", "Bearer token for Nexus behind an SSO reverse proxy")
+	fs.StringVar(&o.CredentialHelper, "credential-helper", "", "Command that prints credentials as JSON, like a Docker credential helper; called as '<command> get' with the server URL on stdin")
+	fs.IntVar(&o.Retry.MaxAttempts, "retry-max-attempts", retryPolicy.MaxAttempts, "Maximum number of attempts for each Nexus request, including the first one")
+	fs.DurationVar(&o.Retry.BaseDelay, "retry-base-delay", retryPolicy.BaseDelay, "Delay before the first retry; doubles with every attempt")
+	fs.DurationVar(&o.Retry.MaxDelay, "retry-max-delay", retryPolicy.MaxDelay, "Upper bound for the retry delay, including Retry-After")
+	fs.DurationVar(&o.HTTP.ConnectTimeout, "connect-timeout", defaultHTTPClientConfig.ConnectTimeout, "Timeout for establishing a connection, including the TLS handshake")
+	fs.DurationVar(&o.HTTP.ResponseHeaderTimeout, "response-header-timeout", defaultHTTPClientConfig.ResponseHeaderTimeout, "Timeout for waiting for response headers after a request is sent")
+	fs.DurationVar(&o.HTTP.IdleConnTimeout, "idle-conn-timeout", defaultHTTPClientConfig.IdleConnTimeout, "How long an idle keep-alive connection stays in the pool")
+	fs.StringVar(&o.HTTP.CACertFile, "ca-cert", "", "PEM file with additional CA certificates to trust")
+	fs.BoolVar(&o.HTTP.InsecureSkipVerify, "insecure", false, "Skip TLS certificate verification (lab instances only)")
+	fs.StringVar(&o.HTTP.ClientCertFile, "client-cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&o.HTTP.ClientKeyFile, "client-key", "", "PEM private key for -client-cert")
+	fs.StringVar(&o.ConfigPath, "config", defaultConfigPath(), "Path to the YAML config file with Nexus profiles")
+	fs.StringVar(&o.Profile, "profile", "", "Config profile to use (default: NEXUS_PROFILE or default-profile from the config)")
+}
+
+// repoNameFlag — репозиторий, с которым работает команда.
+func repoNameFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.RepoName, "repo-name", "", "Name of the repository (e.g., maven-test)")
+}
+
+// repoTypeFlag — формат репозитория; если не задан, определяется через API Nexus.
+func repoTypeFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.RepoType, "repo-type", "", "Type of repository: '"+strings.Join(repoTypes(), "', '")+"' (default: detected from the repository format)")
+}
+
+// targetFlags — второй репозиторий для migrate, diff и sync.
+func targetFlags(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.TargetURL, "target-url", "", "Base URL of the target Nexus (default: -repo-url)")
+	fs.StringVar(&o.TargetRepo, "target-repo", "", "Name of the target repository")
+	fs.StringVar(&o.TargetUsername, "target-username", "", "Username for the target Nexus (optional)")
+	fs.StringVar(&o.TargetPassword, "target-password", "", "Password for the target Nexus (optional)")
+	fs.StringVar(&o.TargetUserToken, "target-user-token", "", "Nexus user token for the target Nexus as <name code>:<pass code>")
+	fs.StringVar(&o.TargetBearerToken, "target-bearer-token", "", "Bearer token for the target Nexus")
+}
+
+func workersFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.IntVar(&o.Workers, "workers", 10, "Number of concurrent workers for upload/download")
+}
+
+func dryRunFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.BoolVar(&o.DryRun, "dry-run", false, "Perform a dry run without making any changes")
+}
+
+func journalFlags(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.Journal, "journal", "", "Path to the run journal (default: nexus-operator-<command>-<repo-name>.journal.jsonl)")
+	fs.BoolVar(&o.Resume, "resume", false, "Continue an interrupted run from its journal, skipping completed items")
+}
+
+func reportFlags(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.ReportPath, "report", "", "Write a machine-readable report with per-file results to this path")
+	fs.StringVar(&o.ReportFormat, "report-format", reportJSON, "Report format: '"+reportJSON+"' or '"+reportJUnit+"'")
+}
+
+func queueSizeFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.IntVar(&o.QueueSize, "queue-size", 1000, "Maximum number of listed assets waiting for download workers")
+}
+
+func incrementalFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.BoolVar(&o.Incremental, "incremental", false, "Export only new or changed assets, skipping files that are already present and unchanged")
+}
+
+func simpleIndexFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.BoolVar(&o.SimpleIndex, "simple-index", false, "Build a PEP 503 simple/ index in the export directory for pip --index-url file:// (pypi)")
+}
+
+func importDirFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.ImportDir, "import-dir", "", "Directory to import files from")
+}
+
+func onConflictFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.OnConflict, "on-conflict", conflictOverwrite, "What to do with files that already exist in the repository: '"+conflictSkip+"' checks first and leaves them alone, '"+conflictOverwrite+"' uploads anyway, '"+conflictFail+"' stops the import at the first conflict")
+}
+
+func mavenComponentsFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.BoolVar(&o.MavenComponents, "maven-components", false, "Upload Maven files through the components API so Nexus rebuilds maven-metadata.xml and checksums")
+}
+
+func nugetAPIKeyFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.NugetAPIKey, "nuget-api-key", "", "NuGet API key sent as X-NuGet-ApiKey when pushing packages (or NEXUS_NUGET_API_KEY)")
+}
+
+func localDirFlag(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.LocalDir, "local-dir", "", "Local tree in export layout to compare with the repository")
+}
+
+func syncFlags(fs *flag.FlagSet, o *cliOptions) {
+	fs.StringVar(&o.Direction, "direction", syncFromRepo, "Sync direction: '"+syncFromRepo+"' copies -repo-name to the other side, '"+syncToRepo+"' copies the other side into -repo-name")
+	fs.BoolVar(&o.Delete, "delete", false, "Delete files on the receiving side that are absent on the reference side")
+}
+
+// requireOtherSide проверяет, что для diff и sync задана ровно одна вторая сторона.
+func requireOtherSide(o *cliOptions) error {
+	if (o.LocalDir == "") == (o.TargetRepo == "") {
+		return usageError("provide either -local-dir or -target-repo")
+	}
+	return nil
+}
+
+// diffOptions собирает стороны сравнения для diff, verify и sync.
+func (o *cliOptions) diffOptions() DiffOptions {
+	return DiffOptions{
+		RepoURL:    o.RepoURL,
+		RepoName:   o.RepoName,
+		RepoType:   o.RepoType,
+		Auth:       o.Auth,
+		LocalDir:   o.LocalDir,
+		TargetURL:  o.TargetURL,
+		TargetRepo: o.TargetRepo,
+		TargetAuth: o.TargetAuth,
+		Workers:    o.Workers,
+	}
+}
+
+// --- Команды ---
+
+// commands возвращает все подкоманды в порядке, в котором они показываются в справке.
+func commands() []*command {
+	return []*command{
+		{
+			Name:        "export",
+			Usage:       "-repo-url=URL -repo-name=NAME [flags]",
+			Summary:     "Download all assets of a repository into a local directory",
+			Description: "Downloads every asset of -repo-name into ./<repo-name>, keeping the repository layout.\nChecksums are verified, interrupted runs can be resumed from the journal.",
+			Examples: []string{
+				programName + " export -repo-url=https://nexus.example.com -repo-name=maven-releases",
+				programName + " export -repo-url=https://nexus.example.com -repo-name=npm-internal -incremental",
+				programName + " export -repo-url=https://nexus.example.com -repo-name=pypi-internal -repo-type=pypi -simple-index",
+			},
+			Flags:    []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, workersFlag, queueSizeFlag, incrementalFlag, simpleIndexFlag, dryRunFlag, journalFlags, reportFlags},
+			Required: []string{"repo-url", "repo-name"},
+			RepoType: repoTypeOptional,
+			Title:    "Export",
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return ExportFiles(ctx, ExportOptions{
+					RepoURL:     o.RepoURL,
+					RepoName:    o.RepoName,
+					RepoType:    o.RepoType,
+					Auth:        o.Auth,
+					DryRun:      o.DryRun,
+					Incremental: o.Incremental,
+					SimpleIndex: o.SimpleIndex,
+					Workers:     o.Workers,
+					QueueSize:   o.QueueSize,
+					Journal:     o.Journal,
+					Resume:      o.Resume,
+					Report:      o.Report,
+				})
+			},
+		},
+		{
+			Name:        "import",
+			Usage:       "-repo-url=URL -repo-name=NAME -import-dir=DIR [flags]",
+			Summary:     "Upload files from a local directory into a repository",
+			Description: "Uploads every file under -import-dir into -repo-name using the upload API of the repository format.",
+			Examples: []string{
+				programName + " import -repo-url=https://nexus.example.com -repo-name=maven-releases -import-dir=./maven-releases",
+				programName + " import -repo-url=https://nexus.example.com -repo-name=pypi-internal -repo-type=pypi -import-dir=./dist -dry-run",
+				programName + " import -repo-url=https://nexus.example.com -repo-name=maven-releases -import-dir=./maven-releases -on-conflict=skip",
+			},
+			Flags:    []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, importDirFlag, onConflictFlag, mavenComponentsFlag, nugetAPIKeyFlag, workersFlag, dryRunFlag, journalFlags, reportFlags},
+			Required: []string{"repo-url", "repo-name", "import-dir"},
+			RepoType: repoTypeRequired,
+			Validate: func(o *cliOptions) error {
+				switch o.OnConflict {
+				case conflictSkip, conflictOverwrite, conflictFail:
+					return nil
+				}
+				return usageError(fmt.Sprintf("invalid -on-conflict %q, use '%s', '%s' or '%s'", o.OnConflict, conflictSkip, conflictOverwrite, conflictFail))
+			},
+			Title: "Import",
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return ImportFiles(ctx, ImportOptions{
+					RepoURL:         o.RepoURL,
+					RepoName:        o.RepoName,
+					RepoType:        o.RepoType,
+					ImportDir:       o.ImportDir,
+					Auth:            o.Auth,
+					DryRun:          o.DryRun,
+					Workers:         o.Workers,
+					OnConflict:      o.OnConflict,
+					MavenComponents: o.MavenComponents,
+					NugetAPIKey:     o.NugetAPIKey,
+					Journal:         o.Journal,
+					Resume:          o.Resume,
+					Report:          o.Report,
+				})
+			},
+		},
+		{
+			Name:        "migrate",
+			Usage:       "-repo-url=URL -repo-name=NAME -target-repo=NAME [flags]",
+			Summary:     "Copy all assets of a repository into another repository",
+			Description: "Streams every asset of -repo-name into -target-repo without storing files on disk.\nThe target may live on another Nexus (-target-url).",
+			Examples: []string{
+				programName + " migrate -repo-url=https://old.example.com -repo-name=maven-releases -target-url=https://new.example.com -target-repo=maven-releases",
+			},
+			Flags:    []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, targetFlags, nugetAPIKeyFlag, workersFlag, queueSizeFlag, dryRunFlag, journalFlags, reportFlags},
+			Required: []string{"repo-url", "repo-name", "target-repo"},
+			RepoType: repoTypeRequired,
+			Title:    "Migration",
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return MigrateRepository(ctx, MigrateOptions{
+					SourceURL:   o.RepoURL,
+					SourceRepo:  o.RepoName,
+					SourceAuth:  o.Auth,
+					TargetURL:   o.TargetURL,
+					TargetRepo:  o.TargetRepo,
+					TargetAuth:  o.TargetAuth,
+					RepoType:    o.RepoType,
+					DryRun:      o.DryRun,
+					Workers:     o.Workers,
+					QueueSize:   o.QueueSize,
+					NugetAPIKey: o.NugetAPIKey,
+					Journal:     o.Journal,
+					Resume:      o.Resume,
+					Report:      o.Report,
+				})
+			},
+		},
+		{
+			Name:        "diff",
+			Usage:       "-repo-url=URL -repo-name=NAME (-local-dir=DIR | -target-repo=NAME) [flags]",
+			Summary:     "Compare a repository with a local tree or another repository",
+			Description: "Prints missing, extra and different files. Exits with code 3 if the sides differ.",
+			Examples: []string{
+				programName + " diff -repo-url=https://nexus.example.com -repo-name=maven-releases -local-dir=./maven-releases",
+				programName + " diff -repo-url=https://nexus.example.com -repo-name=maven-releases -target-url=https://mirror.example.com -target-repo=maven-releases",
+			},
+			Flags:       []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, localDirFlag, targetFlags, workersFlag},
+			Required:    []string{"repo-url", "repo-name"},
+			RepoType:    repoTypeOptional,
+			Validate:    requireOtherSide,
+			Title:       "Diff",
+			Silent:      true,
+			Differences: true,
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				if err := DiffRepository(ctx, o.diffOptions()); err != nil {
+					return err
+				}
+				fmt.Println("No differences found.")
+				return nil
+			},
+		},
+		{
+			Name:        "sync",
+			Usage:       "-repo-url=URL -repo-name=NAME (-local-dir=DIR | -target-repo=NAME) [flags]",
+			Summary:     "Copy only missing and changed files between two sides",
+			Description: "Brings the receiving side in line with the reference side chosen by -direction.\nWith -delete, files absent on the reference side are removed.",
+			Examples: []string{
+				programName + " sync -repo-url=https://nexus.example.com -repo-name=maven-releases -local-dir=./maven-releases",
+				programName + " sync -repo-url=https://nexus.example.com -repo-name=maven-releases -target-repo=maven-mirror -delete -dry-run",
+			},
+			Flags:    []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, localDirFlag, targetFlags, syncFlags, workersFlag, dryRunFlag, reportFlags},
+			Required: []string{"repo-url", "repo-name"},
+			RepoType: repoTypeOptional,
+			Validate: requireOtherSide,
+			Title:    "Sync",
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return SyncRepository(ctx, SyncOptions{
+					DiffOptions: o.diffOptions(),
+					Direction:   o.Direction,
+					Delete:      o.Delete,
+					DryRun:      o.DryRun,
+					Report:      o.Report,
+				})
+			},
+		},
+		{
+			Name:        "verify",
+			Usage:       "-repo-url=URL -repo-name=NAME -local-dir=DIR [flags]",
+			Summary:     "Check that a local export is complete and intact",
+			Description: "Checks that every asset of -repo-name is present in -local-dir with the same size and checksum.\nExtra local files are ignored. Exits with code 3 if files are missing or corrupted.",
+			Examples: []string{
+				programName + " verify -repo-url=https://nexus.example.com -repo-name=maven-releases -local-dir=./maven-releases",
+			},
+			Flags:       []flagGroup{connectionFlags, repoNameFlag, repoTypeFlag, localDirFlag, workersFlag},
+			Required:    []string{"repo-url", "repo-name", "local-dir"},
+			RepoType:    repoTypeOptional,
+			Title:       "Verify",
+			Differences: true,
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return VerifyExport(ctx, o.diffOptions())
+			},
+		},
+		{
+			Name:        "list",
+			Usage:       "-repo-url=URL -repo-name=NAME [flags]",
+			Summary:     "List assets of a repository",
+			Description: "Prints path, size and sha1 of every asset, separated by tabs.",
+			Examples: []string{
+				programName + " list -repo-url=https://nexus.example.com -repo-name=maven-releases | grep -c '\\.jar'",
+			},
+			Flags:    []flagGroup{connectionFlags, repoNameFlag},
+			Required: []string{"repo-url", "repo-name"},
+			Title:    "List",
+			Silent:   true,
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return ListAssets(ctx, o.RepoURL, o.RepoName, o.Auth, os.Stdout)
+			},
+		},
+		{
+			Name:        "repos",
+			Usage:       "-repo-url=URL [flags]",
+			Summary:     "List repositories of a Nexus instance",
+			Description: "Prints name, -repo-type value, kind (hosted, proxy or group) and URL of every repository.",
+			Examples: []string{
+				programName + " repos -repo-url=https://nexus.example.com",
+			},
+			Flags:    []flagGroup{connectionFlags},
+			Required: []string{"repo-url"},
+			Title:    "Repos",
+			Silent:   true,
+			Run: func(ctx context.Context, o *cliOptions, _ []string) error {
+				return ListRepositories(ctx, o.RepoURL, o.Auth, os.Stdout)
+			},
+		},
+		{
+			Name:        "completion",
+			Usage:       "bash|zsh|fish",
+			Summary:     "Print a shell completion script",
+			Description: "Prints a completion script for the given shell to stdout.",
+			Examples: []string{
+				"source <(" + programName + " completion bash)",
+				programName + " completion zsh > \"${fpath[1]}/_" + programName + "\"",
+				programName + " completion fish > ~/.config/fish/completions/" + programName + ".fish",
+			},
+			Title:   "Completion",
+			Silent:  true,
+			Offline: true,
+			Run: func(_ context.Context, _ *cliOptions, args []string) error {
+				if len(args) != 1 {
+					return usageError("expected exactly one shell: bash, zsh or fish")
+				}
+				return writeCompletion(os.Stdout, args[0])
+			},
+		},
+		{
+			Name:        "help",
+			Usage:       "[command]",
+			Summary:     "Show help for a command",
+			Description: "Prints the list of commands or flags, examples and exit codes of a command.",
+			Title:       "Help",
+			Silent:      true,
+			Offline:     true,
+			Run: func(_ context.Context, _ *cliOptions, args []string) error {
+				switch len(args) {
+				case 0:
+					printUsage(os.Stdout)
+					return nil
+				case 1:
+					cmd := findCommand(args[0])
+					if cmd == nil {
+						return usageError(fmt.Sprintf("unknown command %q", args[0]))
+					}
+					cmd.printHelp(os.Stdout, cmd.flagSet(&cliOptions{}))
+					return nil
+				}
+				return usageError("expected at most one command")
+			},
+		},
+	}
+}
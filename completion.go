@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// completionFlag — флаг команды в том виде, который нужен скриптам автодополнения.
+type completionFlag struct {
+	Name   string
+	Usage  string
+	Bool   bool     // флаг без значения
+	Values []string // допустимые значения, если их конечный набор
+	File   bool     // значение — путь к файлу или директории
+}
+
+// fileFlags — флаги, значения которых дополняются путями.
+var fileFlags = map[string]bool{
+	"import-dir":        true,
+	"local-dir":         true,
+	"journal":           true,
+	"report":            true,
+	"config":            true,
+	"ca-cert":           true,
+	"client-cert":       true,
+	"client-key":        true,
+	"credential-helper": true,
+}
+
+// flagValues возвращает допустимые значения флагов с конечным набором значений.
+func flagValues() map[string][]string {
+	return map[string][]string{
+		"repo-type":     repoTypes(),
+		"direction":     {syncFromRepo, syncToRepo},
+		"report-format": {reportJSON, reportJUnit},
+		"on-conflict":   {conflictSkip, conflictOverwrite, conflictFail},
+	}
+}
+
+// completionShells — оболочки, для которых генерируются скрипты.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionFlags возвращает флаги команды в порядке регистрации групп.
+func completionFlags(cmd *command) []completionFlag {
+	values := flagValues()
+	fs := cmd.flagSet(&cliOptions{})
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			Name:   f.Name,
+			Usage:  f.Usage,
+			Bool:   ok && boolFlag.IsBoolFlag(),
+			Values: values[f.Name],
+			File:   fileFlags[f.Name],
+		})
+	})
+	return flags
+}
+
+// writeCompletion печатает скрипт автодополнения для оболочки shell.
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w)
+	case "zsh":
+		return writeZshCompletion(w)
+	case "fish":
+		return writeFishCompletion(w)
+	}
+	return usageError(fmt.Sprintf("unsupported shell %q, use bash, zsh or fish", shell))
+}
+
+// commandArgs — позиционные аргументы команд, которые тоже дополняются.
+func commandArgs(cmd *command) []string {
+	switch cmd.Name {
+	case "completion":
+		return completionShells
+	case "help":
+		var names []string
+		for _, c := range commands() {
+			names = append(names, c.Name)
+		}
+		return names
+	}
+	return nil
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := "_" + strings.ReplaceAll(programName, "-", "_")
+	var names []string
+	for _, cmd := range commands() {
+		names = append(names, cmd.Name)
+	}
+
+	fmt.Fprintf(&b, "# bash completion for %s\n", programName)
+	fmt.Fprintf(&b, "# Install: source <(%s completion bash)\n\n", programName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur prev cmd\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("    cmd=\"${COMP_WORDS[1]}\"\n\n")
+	b.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&b, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+
+	// Значения флагов: перечисления дополняются списком, пути — средствами bash (complete -o default).
+	b.WriteString("    case \"$prev\" in\n")
+	values := flagValues()
+	for _, name := range sortedKeys(values) {
+		fmt.Fprintf(&b, "        -%s|--%s)\n", name, name)
+		fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(values[name], " "))
+		b.WriteString("            return\n")
+		b.WriteString("            ;;\n")
+	}
+	var valueFlags []string
+	seen := map[string]bool{}
+	for _, cmd := range commands() {
+		for _, f := range completionFlags(cmd) {
+			if !f.Bool && f.Values == nil && !seen[f.Name] {
+				seen[f.Name] = true
+				valueFlags = append(valueFlags, "-"+f.Name, "--"+f.Name)
+			}
+		}
+	}
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(&b, "        %s)\n", strings.Join(valueFlags, "|"))
+		b.WriteString("            return\n")
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    case \"$cmd\" in\n")
+	for _, cmd := range commands() {
+		var words []string
+		for _, f := range completionFlags(cmd) {
+			words = append(words, "-"+f.Name)
+		}
+		words = append(words, commandArgs(cmd)...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s)\n", cmd.Name)
+		fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(words, " "))
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, programName)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := "_" + strings.ReplaceAll(programName, "-", "_")
+
+	fmt.Fprintf(&b, "#compdef %s\n", programName)
+	fmt.Fprintf(&b, "# zsh completion for %s\n", programName)
+	fmt.Fprintf(&b, "# Install: %s completion zsh > \"${fpath[1]}/_%s\"\n\n", programName, programName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local -a commands\n")
+	b.WriteString("    commands=(\n")
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "        %s\n", zshQuote(cmd.Name+":"+cmd.Summary))
+	}
+	b.WriteString("    )\n\n")
+	b.WriteString("    if (( CURRENT == 2 )); then\n")
+	b.WriteString("        _describe 'command' commands\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n\n")
+	b.WriteString("    local cmd=$words[2]\n")
+	b.WriteString("    shift words\n")
+	b.WriteString("    (( CURRENT-- ))\n\n")
+	b.WriteString("    case $cmd in\n")
+	for _, cmd := range commands() {
+		var specs []string
+		for _, f := range completionFlags(cmd) {
+			spec := "-" + f.Name + "[" + zshDescription(f.Usage) + "]"
+			switch {
+			case f.Bool:
+			case f.Values != nil:
+				spec += ":" + f.Name + ":(" + strings.Join(f.Values, " ") + ")"
+			case f.File:
+				spec += ":" + f.Name + ":_files"
+			default:
+				spec += ":" + f.Name + ":"
+			}
+			specs = append(specs, zshQuote(spec))
+		}
+		if args := commandArgs(cmd); args != nil {
+			specs = append(specs, zshQuote("1:argument:("+strings.Join(args, " ")+")"))
+		}
+		if len(specs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s)\n", cmd.Name)
+		fmt.Fprintf(&b, "            _arguments \\\n                %s\n", strings.Join(specs, " \\\n                "))
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, programName)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	c := "complete -c " + programName
+
+	fmt.Fprintf(&b, "# fish completion for %s\n", programName)
+	fmt.Fprintf(&b, "# Install: %s completion fish > ~/.config/fish/completions/%s.fish\n\n", programName, programName)
+	fmt.Fprintf(&b, "%s -f\n", c)
+	for _, cmd := range commands() {
+		fmt.Fprintf(&b, "%s -n __fish_use_subcommand -a %s -d %s\n", c, cmd.Name, fishQuote(cmd.Summary))
+	}
+	for _, cmd := range commands() {
+		cond := fishQuote("__fish_seen_subcommand_from " + cmd.Name)
+		for _, f := range completionFlags(cmd) {
+			// Флаги Go с одним дефисом — это "старые" опции fish (-o).
+			line := fmt.Sprintf("%s -n %s -o %s", c, cond, f.Name)
+			switch {
+			case f.Bool:
+			case f.Values != nil:
+				line += " -x -a " + fishQuote(strings.Join(f.Values, " "))
+			case f.File:
+				line += " -r -F"
+			default:
+				line += " -x"
+			}
+			fmt.Fprintf(&b, "%s -d %s\n", line, fishQuote(shortUsage(f.Usage)))
+		}
+		if args := commandArgs(cmd); args != nil {
+			fmt.Fprintf(&b, "%s -n %s -a %s\n", c, cond, fishQuote(strings.Join(args, " ")))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shortUsage сокращает описание флага до первой фразы: в подсказке оболочки длинный текст мешает.
+func shortUsage(usage string) string {
+	for _, sep := range []string{"; ", " (", ". "} {
+		if i := strings.Index(usage, sep); i > 0 {
+			usage = usage[:i]
+		}
+	}
+	return usage
+}
+
+// zshDescription готовит описание для спецификации _arguments, где скобки и двоеточия служебные.
+func zshDescription(usage string) string {
+	return strings.NewReplacer("[", "(", "]", ")", ":", " -").Replace(shortUsage(usage))
+}
+
+// zshQuote заключает строку в одинарные кавычки shell.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote заключает строку в одинарные кавычки fish, где экранируются только ' и \.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// sortedKeys возвращает ключи карты по алфавиту, чтобы скрипты не менялись от запуска к запуску.
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
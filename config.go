@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config — файл настроек с именованными профилями экземпляров Nexus.
+//
+//	default-profile: prod
+//	profiles:
+//	  prod:
+//	    url: https://nexus.example.com
+//	    username: deployer
+//	    ca-cert: /etc/ssl/corp-ca.pem
+//	    workers: 20
+//	    repo: maven-releases
+//	    repos:
+//	      maven-releases:
+//	        type: maven
+//	      npm-internal:
+//	        type: npm
+//	        workers: 4
+type Config struct {
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// Profile задает подключение к одному экземпляру Nexus и значения флагов по умолчанию.
+type Profile struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	UserToken        string `yaml:"user-token"`        // "<name code>:<pass code>"
+	BearerToken      string `yaml:"bearer-token"`      // для экземпляров за SSO reverse proxy
+	CredentialHelper string `yaml:"credential-helper"` // команда в формате Docker credential helper
+
+	CACert     string `yaml:"ca-cert"`
+	Insecure   bool   `yaml:"insecure"`
+	ClientCert string `yaml:"client-cert"`
+	ClientKey  string `yaml:"client-key"`
+
+	Workers int    `yaml:"workers"`
+	Repo    string `yaml:"repo"` // репозиторий, если -repo-name не задан
+
+	Repos map[string]RepoDefaults `yaml:"repos"`
+}
+
+// RepoDefaults — значения по умолчанию для конкретного репозитория профиля.
+type RepoDefaults struct {
+	Type    string `yaml:"type"`
+	Workers int    `yaml:"workers"` // переопределяет workers профиля
+}
+
+// defaultConfigPath возвращает ~/.config/nexus-operator/config.yaml
+// (или аналог для ОС по os.UserConfigDir).
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "nexus-operator", "config.yaml")
+}
+
+// loadConfig читает файл настроек. Отсутствие файла по умолчанию не ошибка:
+// тогда возвращается пустой Config. Явно указанный файл обязан существовать.
+// Неизвестные ключи считаются ошибкой, чтобы опечатка не превращалась в молча
+// проигнорированную настройку.
+func loadConfig(path string, required bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// profile возвращает профиль по имени, а без имени — профиль default-profile.
+// Если ни то ни другое не задано, возвращается пустой профиль.
+func (c *Config) profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		known := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			known = append(known, n)
+		}
+		sort.Strings(known)
+		return Profile{}, fmt.Errorf("profile %q not found in config, known profiles: %v", name, known)
+	}
+	return profile, nil
+}
+
+// credentialFlags — флаги с учетными данными, из которых resolveAuth выбирает способ аутентификации.
+var credentialFlags = []string{"username", "password", "user-token", "bearer-token", "credential-helper"}
+
+// sameServer сообщает, что два URL Nexus указывают на один сервер, с точностью до регистра и "/" в конце.
+func sameServer(a, b string) bool {
+	return strings.EqualFold(strings.TrimRight(a, "/"), strings.TrimRight(b, "/"))
+}
+
+// applyConfig заполняет флаги значениями профиля. Флаги из skip — заданные
+// в командной строке или через переменные окружения — не трогаются, так что
+// приоритет получается таким: флаги, затем окружение, затем файл настроек.
+func applyConfig(fs *flag.FlagSet, skip map[string]bool, profile Profile) error {
+	values := map[string]string{
+		"repo-url":          profile.URL,
+		"username":          profile.Username,
+		"password":          profile.Password,
+		"user-token":        profile.UserToken,
+		"bearer-token":      profile.BearerToken,
+		"credential-helper": profile.CredentialHelper,
+		"ca-cert":           profile.CACert,
+		"client-cert":       profile.ClientCert,
+		"client-key":        profile.ClientKey,
+		"repo-name":         profile.Repo,
+	}
+	if profile.Insecure {
+		values["insecure"] = "true"
+	}
+	if profile.Workers > 0 {
+		values["workers"] = strconv.Itoa(profile.Workers)
+	}
+
+	// Настройки репозитория ищутся по итоговому -repo-name: из командной строки или из профиля.
+	repoName := profile.Repo
+	if f := fs.Lookup("repo-name"); f != nil && skip["repo-name"] {
+		repoName = f.Value.String()
+	}
+	if repo, ok := profile.Repos[repoName]; ok {
+		values["repo-type"] = repo.Type
+		if repo.Workers > 0 {
+			values["workers"] = strconv.Itoa(repo.Workers)
+		}
+	}
+
+	// Учетные данные профиля отправляются только на его сервер: с default-profile команда
+	// с другим -repo-url не должна передать чужому хосту пароль production.
+	// Явно выбранный профиль (-profile или NEXUS_PROFILE) применяется целиком.
+	dropCredentials := false
+	if f := fs.Lookup("repo-url"); f != nil && skip["repo-url"] && !skip["profile"] && !sameServer(f.Value.String(), profile.URL) {
+		dropCredentials = true
+	}
+	// resolveAuth выбирает способ аутентификации в фиксированном порядке, поэтому bearer-token
+	// профиля оказался бы важнее -username/-password из командной строки. Если хоть одно
+	// учетное значение задано флагом или окружением, учетные данные профиля не применяются.
+	for _, name := range credentialFlags {
+		if skip[name] {
+			dropCredentials = true
+		}
+	}
+	if dropCredentials {
+		for _, name := range credentialFlags {
+			delete(values, name)
+		}
+	}
+
+	for name, value := range values {
+		// Флаги, которых нет у команды (например, -workers у repos), пропускаются.
+		if value == "" || skip[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid config value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
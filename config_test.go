@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	// 1. Файл настроек с двумя профилями и настройками репозитория
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `default-profile: prod
+profiles:
+  prod:
+    url: https://nexus.example.com
+    username: deployer
+    password: from-config
+    workers: 20
+    repo: npm-internal
+    repos:
+      npm-internal:
+        type: npm
+        workers: 4
+  lab:
+    url: https://lab.example.com
+    insecure: true
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	profile, err := cfg.profile("")
+	if err != nil {
+		t.Fatalf("Expected default profile, got %v", err)
+	}
+
+	// 2. -repo-url задан флагом, пароль — через окружение
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	repoURL := fs.String("repo-url", "", "")
+	repoName := fs.String("repo-name", "", "")
+	repoType := fs.String("repo-type", "", "")
+	username := fs.String("username", "", "")
+	password := fs.String("password", "", "")
+	workers := fs.Int("workers", 10, "")
+	for _, name := range []string{"ca-cert", "client-cert", "client-key"} {
+		fs.String(name, "", "")
+	}
+	insecure := fs.Bool("insecure", false, "")
+	if err := fs.Parse([]string{"-repo-url=https://override.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	skip := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { skip[f.Name] = true })
+	fs.Set("password", "from-env")
+	skip["password"] = true
+
+	if err := applyConfig(fs, skip, profile); err != nil {
+		t.Fatalf("applyConfig failed: %v", err)
+	}
+
+	// 3. Флаг важнее окружения, окружение важнее профиля, настройки репозитория важнее профиля
+	if *repoURL != "https://override.example.com" {
+		t.Errorf("Expected flag to win, got repo-url %q", *repoURL)
+	}
+	if *password != "from-env" {
+		t.Errorf("Expected env to win over config, got password %q", *password)
+	}
+	if *repoName != "npm-internal" || *repoType != "npm" {
+		t.Errorf("Expected profile values, got repo-name=%q repo-type=%q", *repoName, *repoType)
+	}
+	// Учетные данные профиля не уходят на другой сервер
+	if *username != "" {
+		t.Errorf("Expected profile username not to be sent to another host, got %q", *username)
+	}
+	if *workers != 4 {
+		t.Errorf("Expected per-repo workers 4, got %d", *workers)
+	}
+	if *insecure {
+		t.Error("Expected insecure from another profile not to be applied")
+	}
+
+	// 4. Неизвестный профиль — понятная ошибка
+	if _, err := cfg.profile("staging"); err == nil || !strings.Contains(err.Error(), "lab") {
+		t.Errorf("Expected error listing known profiles, got %v", err)
+	}
+}
+
+func TestApplyConfigCredentials(t *testing.T) {
+	profile := Profile{URL: "https://nexus.example.com", Username: "deployer", Password: "secret", BearerToken: "token"}
+	tests := []struct {
+		name  string
+		args  []string
+		apply bool
+	}{
+		{"profile url", nil, true},
+		{"same host", []string{"-repo-url=https://NEXUS.example.com/"}, true},
+		{"other host", []string{"-repo-url=https://other.example.com"}, false},
+		{"explicit profile", []string{"-repo-url=https://other.example.com", "-profile=prod"}, true},
+		{"username flag", []string{"-username=admin", "-password=admin123"}, false},
+		{"helper flag", []string{"-credential-helper=nexus-helper"}, false},
+	}
+	for _, tt := range tests {
+		// 1. Набор флагов команды с учетными данными
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("repo-url", "", "")
+		fs.String("profile", "", "")
+		credentials := map[string]*string{}
+		for _, name := range credentialFlags {
+			credentials[name] = fs.String(name, "", "")
+		}
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		skip := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { skip[f.Name] = true })
+
+		// 2. Учетные данные профиля применяются только для его сервера или явно выбранного профиля
+		// и только если ни одно учетное значение не задано флагом
+		if err := applyConfig(fs, skip, profile); err != nil {
+			t.Fatalf("%s: applyConfig failed: %v", tt.name, err)
+		}
+		applied := *credentials["username"] == "deployer" && *credentials["password"] == "secret" && *credentials["bearer-token"] == "token"
+		none := *credentials["bearer-token"] == "" && !strings.Contains(*credentials["username"]+*credentials["password"], "deployer") &&
+			*credentials["password"] != "secret"
+		if tt.apply && !applied || !tt.apply && !none {
+			t.Errorf("%s: expected credentials applied=%v, got username=%q password=%q bearer-token=%q",
+				tt.name, tt.apply, *credentials["username"], *credentials["password"], *credentials["bearer-token"])
+		}
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	// 1. Файла по умолчанию может не быть, явно указанный обязателен
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := loadConfig(missing, false); err != nil {
+		t.Errorf("Expected missing default config to be ignored, got %v", err)
+	}
+	if _, err := loadConfig(missing, true); err == nil {
+		t.Error("Expected error for missing explicit config")
+	}
+
+	// 2. Опечатка в ключе не игнорируется молча
+	typo := filepath.Join(dir, "typo.yaml")
+	if err := os.WriteFile(typo, []byte("profiles:\n  prod:\n    urll: https://nexus.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(typo, true); err == nil {
+		t.Error("Expected error for unknown config key")
+	}
+}
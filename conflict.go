@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Политики импорта для файлов, которые уже есть в репозитории (-on-conflict).
+const (
+	conflictSkip      = "skip"      // проверить наличие до загрузки и пропустить найденные
+	conflictOverwrite = "overwrite" // загружать всегда; Nexus заменит ассет, если политика записи разрешает redeploy
+	conflictFail      = "fail"      // остановить импорт на первом конфликте
+)
+
+// importPresent — итог импорта для файла, который уже есть в репозитории.
+const importPresent = "present"
+
+// pathUploader — загрузчик, который кладет артефакт по предсказуемому пути в репозитории.
+// Наличие такого артефакта проверяется одним запросом HEAD; для остальных форматов
+// путь назначает Nexus, и артефакт ищется по контрольной сумме.
+type pathUploader interface {
+	RepositoryPath(artifact Artifact) (string, error)
+}
+
+// artifactExists проверяет, есть ли артефакт в репозитории.
+func artifactExists(ctx context.Context, repoURL, repoName string, uploader Uploader, artifact Artifact, auth Auth) (bool, error) {
+	if p, ok := uploader.(pathUploader); ok {
+		repoPath, err := p.RepositoryPath(artifact)
+		if err != nil {
+			return false, err
+		}
+		return headAsset(ctx, fmt.Sprintf("%s/repository/%s/%s", repoURL, repoName, repoPath), auth)
+	}
+
+	sum, err := artifactSHA1(ctx, artifact)
+	if err != nil {
+		return false, err
+	}
+	return searchAssetBySHA1(ctx, repoURL, repoName, sum, auth)
+}
+
+// headAsset проверяет наличие ассета по URL: 200 — есть, 404 — нет.
+func headAsset(ctx context.Context, assetURL string, auth Auth) (bool, error) {
+	resp, err := executeNexusRequest(ctx, "HEAD", assetURL, "", nil, auth)
+	if err != nil {
+		return false, fmt.Errorf("failed to check asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, statusError("failed to check asset", resp)
+}
+
+// searchAssetBySHA1 ищет в репозитории ассет с контрольной суммой sum.
+func searchAssetBySHA1(ctx context.Context, repoURL, repoName, sum string, auth Auth) (bool, error) {
+	apiURL := fmt.Sprintf("%s/service/rest/v1/search/assets?repository=%s&sha1=%s", repoURL, url.QueryEscape(repoName), sum)
+	resp, err := executeNexusRequest(ctx, "GET", apiURL, "", nil, auth)
+	if err != nil {
+		return false, fmt.Errorf("failed to search asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, statusError("failed to search asset", resp)
+	}
+	var result SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return len(result.Items) > 0, nil
+}
+
+// artifactSHA1 считает sha1 содержимого артефакта.
+func artifactSHA1(ctx context.Context, artifact Artifact) (string, error) {
+	body, err := artifact.Open(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	hash := sha1.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", artifact.Path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
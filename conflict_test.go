@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestImportOnConflictSkip(t *testing.T) {
+	// 1. exists.txt уже есть в репозитории, new.txt — нет
+	var puts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/repository/test-raw/exists.txt":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && r.URL.Path == "/repository/test-raw/new.txt":
+			atomic.AddInt32(&puts, 1)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	// 2. Загружается только отсутствующий файл, найденный отмечен в отчете как present
+	dir := writeImportFiles(t, "exists.txt", "new.txt")
+	report := newReport("import", server.URL, "test-raw", false)
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:    server.URL,
+		RepoName:   "test-raw",
+		RepoType:   "raw",
+		ImportDir:  dir,
+		Workers:    2,
+		OnConflict: conflictSkip,
+		Journal:    filepath.Join(t.TempDir(), "journal.jsonl"),
+		Report:     report,
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if puts != 1 {
+		t.Errorf("Expected 1 upload, got %d", puts)
+	}
+	report.Finish(nil)
+	if report.Totals.Skipped != 1 || report.Totals.Succeeded != 1 {
+		t.Errorf("Expected 1 present and 1 uploaded file, got %+v", report.Totals)
+	}
+}
+
+func TestArtifactExistsByChecksum(t *testing.T) {
+	// 1. Для npm путь назначает Nexus, поэтому наличие проверяется поиском по sha1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service/rest/v1/search/assets" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var items []Asset
+		if r.URL.Query().Get("sha1") == sha1HelloWorld {
+			items = []Asset{{Path: "pkg/-/pkg-1.0.0.tgz"}}
+		}
+		json.NewEncoder(w).Encode(SearchResult{Items: items})
+	}))
+	defer server.Close()
+
+	dir := writeImportFiles(t, "pkg-1.0.0.tgz")
+	artifact := mustLocalArtifact(t, filepath.Join(dir, "pkg-1.0.0.tgz"), dir)
+	exists, err := artifactExists(context.Background(), server.URL, "npm-internal", &NpmUploader{}, artifact, nil)
+	if err != nil || !exists {
+		t.Errorf("Expected artifact to be found by checksum, got %v (%v)", exists, err)
+	}
+}
+
+func TestImportRedeployConflict(t *testing.T) {
+	// 1. Репозиторий с политикой "Disable redeploy" отвечает 400 на повторную загрузку
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Repository does not allow updating assets: test-raw"))
+	}))
+	defer server.Close()
+
+	// 2. С -on-conflict=fail импорт останавливается и завершается ошибкой конфликта
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:    server.URL,
+		RepoName:   "test-raw",
+		RepoType:   "raw",
+		ImportDir:  writeImportFiles(t, "a.txt", "b.txt", "c.txt"),
+		Workers:    1,
+		OnConflict: conflictFail,
+		Journal:    filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	var partial *PartialFailure
+	if errors.As(err, &partial) {
+		t.Error("Expected the run to stop, not to report a partial failure")
+	}
+	if code := exitCodeFor(err); code != exitConflict {
+		t.Errorf("Expected exit code %d, got %d", exitConflict, code)
+	}
+}
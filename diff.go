@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errDifferencesFound возвращается из diff, если стороны различаются, чтобы
+// скрипты могли проверять зеркала по коду выхода.
+var errDifferencesFound = errors.New("differences found")
+
+// diffKind — вид расхождения между репозиторием и другой стороной сравнения.
+type diffKind string
+
+const (
+	diffMissing   diffKind = "missing"   // ассет есть в репозитории, на другой стороне его нет
+	diffExtra     diffKind = "extra"     // файл есть только на другой стороне
+	diffDifferent diffKind = "different" // есть на обеих сторонах, но содержимое отличается
+)
+
+// diffItem — файл на одной из сторон сравнения: ассет Nexus или локальный файл.
+type diffItem struct {
+	Asset     *Asset // nil для локального файла
+	LocalPath string // пуст для ассета Nexus
+}
+
+// diffEntry описывает одно расхождение. Key — путь в раскладке экспорта
+// (Exporter.GetLocalPath), по нему сопоставляются стороны.
+type diffEntry struct {
+	Kind  diffKind
+	Key   string
+	Repo  *Asset    // ассет репозитория -repo-name; nil для extra
+	Other *diffItem // файл другой стороны; nil для missing
+}
+
+// DiffOptions задает стороны сравнения: репозиторий Nexus и либо локальное дерево
+// в раскладке экспорта (LocalDir), либо второй репозиторий (TargetRepo).
+type DiffOptions struct {
+	RepoURL  string
+	RepoName string
+	RepoType string
+	Auth     Auth
+
+	LocalDir string
+
+	TargetURL  string
+	TargetRepo string
+	TargetAuth Auth
+
+	Workers int
+}
+
+// comparesRepos сообщает, что другая сторона — репозиторий Nexus, а не директория.
+func (o DiffOptions) comparesRepos() bool {
+	return o.TargetRepo != ""
+}
+
+// otherSide возвращает описание другой стороны для сообщений.
+func (o DiffOptions) otherSide() string {
+	if o.comparesRepos() {
+		return o.TargetURL + "/repository/" + o.TargetRepo
+	}
+	return o.LocalDir
+}
+
+// DiffRepository выводит ассеты, которых нет на другой стороне, лишние файлы
+// и файлы с другим содержимым. Если расхождения есть, возвращает errDifferencesFound.
+func DiffRepository(ctx context.Context, opts DiffOptions) error {
+	entries, same, err := compareSides(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	counts := map[diffKind]int{}
+	for _, entry := range entries {
+		counts[entry.Kind]++
+		fmt.Printf("%-9s %s\n", entry.Kind, entry.Key)
+	}
+	fmt.Printf("Сравнение %s/repository/%s с %s: отсутствует %d, лишних %d, отличается %d, совпадает %d.\n",
+		opts.RepoURL, opts.RepoName, opts.otherSide(),
+		counts[diffMissing], counts[diffExtra], counts[diffDifferent], same)
+
+	if len(entries) > 0 {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+// VerifyExport проверяет, что локальная копия из export полна и не повреждена:
+// каждый ассет репозитория есть в LocalDir и совпадает по содержимому. Лишние
+// локальные файлы ошибкой не считаются — в директории могут лежать и другие данные.
+func VerifyExport(ctx context.Context, opts DiffOptions) error {
+	entries, same, err := compareSides(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, entry := range entries {
+		if entry.Kind == diffExtra {
+			continue
+		}
+		problems++
+		fmt.Printf("%-9s %s\n", entry.Kind, entry.Key)
+	}
+	fmt.Printf("Проверка %s по %s/repository/%s: проблем %d, совпадает %d.\n",
+		opts.LocalDir, opts.RepoURL, opts.RepoName, problems, same)
+
+	if problems > 0 {
+		return errDifferencesFound
+	}
+	return nil
+}
+
+// compareSides листает обе стороны, сопоставляет их по пути и сверяет содержимое
+// общих путей. Возвращает расхождения, отсортированные по пути, и число совпавших файлов.
+func compareSides(ctx context.Context, opts DiffOptions) ([]diffEntry, int, error) {
+	exporter := GetExporter(opts.RepoType)
+
+	repoItems, err := listRepoItems(ctx, opts.RepoURL, opts.RepoName, opts.Auth, exporter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching assets: %w", err)
+	}
+
+	var otherItems map[string]diffItem
+	if opts.comparesRepos() {
+		otherItems, err = listRepoItems(ctx, opts.TargetURL, opts.TargetRepo, opts.TargetAuth, exporter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error fetching target assets: %w", err)
+		}
+	} else {
+		otherItems, err = listLocalItems(opts.LocalDir, exporter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error listing %s: %w", opts.LocalDir, err)
+		}
+	}
+
+	var entries []diffEntry
+	var common []string
+	for key, item := range repoItems {
+		if _, ok := otherItems[key]; ok {
+			common = append(common, key)
+			continue
+		}
+		entries = append(entries, diffEntry{Kind: diffMissing, Key: key, Repo: item.Asset})
+	}
+	for key, item := range otherItems {
+		if _, ok := repoItems[key]; !ok {
+			other := item
+			entries = append(entries, diffEntry{Kind: diffExtra, Key: key, Other: &other})
+		}
+	}
+
+	different, err := compareCommon(ctx, common, opts.Workers, func(key string) (bool, error) {
+		return itemsMatch(*repoItems[key].Asset, otherItems[key])
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, key := range different {
+		other := otherItems[key]
+		entries = append(entries, diffEntry{Kind: diffDifferent, Key: key, Repo: repoItems[key].Asset, Other: &other})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, len(common) - len(different), nil
+}
+
+// compareCommon сверяет общие пути в несколько воркеров: хеширование локальных
+// файлов — самая долгая часть сравнения. Возвращает пути с разным содержимым.
+func compareCommon(ctx context.Context, keys []string, workers int, match func(key string) (bool, error)) ([]string, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		different []string
+		firstErr  error
+	)
+	queue := make(chan string)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for key := range queue {
+				same, err := match(key)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", key, err)
+				}
+				if err == nil && !same {
+					different = append(different, key)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- key
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return different, firstErr
+}
+
+// itemsMatch сравнивает ассет репозитория с файлом другой стороны.
+func itemsMatch(asset Asset, other diffItem) (bool, error) {
+	if other.Asset != nil {
+		return assetsMatch(asset, *other.Asset), nil
+	}
+	// Локальный файл сверяется так же, как при инкрементальном экспорте:
+	// размер, затем контрольная сумма, а без нее — дата изменения.
+	status, err := localFileStatus(downloadTask{
+		FilePath:     other.LocalPath,
+		Checksum:     asset.Checksum,
+		Size:         asset.FileSize,
+		LastModified: asset.LastModified,
+	})
+	return status == exportSkipped, err
+}
+
+// assetsMatch сравнивает два ассета по размеру и самой надежной контрольной сумме,
+// которая есть у обоих. Если сравнить нечем, ассеты считаются совпадающими.
+func assetsMatch(a, b Asset) bool {
+	if a.FileSize > 0 && b.FileSize > 0 && a.FileSize != b.FileSize {
+		return false
+	}
+	for _, algorithm := range checksumAlgorithms {
+		sumA, sumB := a.Checksum[algorithm.Name], b.Checksum[algorithm.Name]
+		if sumA != "" && sumB != "" {
+			return strings.EqualFold(sumA, sumB)
+		}
+	}
+	return true
+}
+
+// listRepoItems читает весь листинг репозитория, ключ — путь в раскладке экспорта.
+// Ассеты, которые экспорт строит заново (например, index.yaml Helm), не сравниваются.
+func listRepoItems(ctx context.Context, repoURL, repoName string, auth Auth, exporter Exporter) (map[string]diffItem, error) {
+	metadata, _ := exporter.(metadataExporter)
+	items := map[string]diffItem{}
+	err := walkAssets(ctx, repoURL, repoName, "", auth, func(assets []Asset, nextToken string) error {
+		for _, asset := range assets {
+			asset := asset
+			key := exporter.GetLocalPath(asset.Path)
+			if metadata != nil && metadata.IsMetadata(key) {
+				continue
+			}
+			items[key] = diffItem{Asset: &asset}
+		}
+		return nil
+	})
+	return items, err
+}
+
+// listLocalItems собирает файлы локального дерева, ключ — путь относительно dir через "/".
+// Недокачанные временные файлы экспорта в сравнении не участвуют.
+// Метаданные, которые экспорт сохраняет рядом с ассетами, пропускаются.
+func listLocalItems(dir string, exporter Exporter) (map[string]diffItem, error) {
+	metadata, _ := exporter.(metadataExporter)
+	items := map[string]diffItem{}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		// Директории еще нет: все ассеты репозитория для нее отсутствующие.
+		return items, nil
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, partialSuffix) {
+			return nil
+		}
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relativePath)
+		if metadata != nil && metadata.IsMetadata(key) {
+			return nil
+		}
+		items[key] = diffItem{LocalPath: path}
+		return nil
+	})
+	return items, err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+const (
+	sha1HelloWorld = "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed" // sha1("hello world")
+	sha1Hello      = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d" // sha1("hello")
+)
+
+func TestDiffLocalTree(t *testing.T) {
+	// 1. В репозитории три ассета
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checksum := map[string]string{"sha1": sha1HelloWorld}
+		json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+			{Path: "same.txt", FileSize: 11, Checksum: checksum},
+			{Path: "dir/changed.txt", Checksum: checksum},
+			{Path: "missing.txt", Checksum: checksum},
+		}})
+	}))
+	defer server.Close()
+
+	// 2. Локальное дерево: совпадающий файл, измененный, лишний и недокачанный
+	dir := t.TempDir()
+	files := map[string]string{
+		"same.txt":                             "hello world",
+		"dir/changed.txt":                      "hello",
+		"extra.txt":                            "extra",
+		"dir/.changed.txt.123" + partialSuffix: "hel",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	opts := DiffOptions{RepoURL: server.URL, RepoName: "raw", RepoType: "raw", LocalDir: dir, Workers: 2}
+	entries, same, err := compareSides(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("compareSides failed: %v", err)
+	}
+
+	// 3. Расхождения отсортированы по пути, временный файл не учитывается
+	want := []struct {
+		Kind diffKind
+		Key  string
+	}{
+		{diffDifferent, "dir/changed.txt"},
+		{diffExtra, "extra.txt"},
+		{diffMissing, "missing.txt"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d differences, got %+v", len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Kind != w.Kind || entries[i].Key != w.Key {
+			t.Errorf("Entry %d: expected %s %s, got %s %s", i, w.Kind, w.Key, entries[i].Kind, entries[i].Key)
+		}
+	}
+	if same != 1 {
+		t.Errorf("Expected 1 matching file, got %d", same)
+	}
+
+	// 4. DiffRepository сообщает о расхождениях ошибкой для кода выхода
+	if err := DiffRepository(context.Background(), opts); !errors.Is(err, errDifferencesFound) {
+		t.Errorf("Expected errDifferencesFound, got %v", err)
+	}
+}
+
+func TestSyncRepositoryBetweenRepos(t *testing.T) {
+	// 1. Эталонный репозиторий: a.txt и b.txt
+	var source *httptest.Server
+	source = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			checksum := map[string]string{"sha1": sha1HelloWorld}
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: source.URL + "/repository/src/a.txt", Path: "a.txt", Checksum: checksum},
+				{DownloadURL: source.URL + "/repository/src/b.txt", Path: "b.txt", Checksum: checksum},
+			}})
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer source.Close()
+
+	// 2. Зеркало: устаревший b.txt и лишний c.txt
+	var mu sync.Mutex
+	requests := map[string]string{}
+	mirror := httptest.NewServer(requireBasicAuth("dst", "dst-secret", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{ID: "b-id", Path: "b.txt", Checksum: map[string]string{"sha1": sha1Hello}},
+				{ID: "c-id", Path: "c.txt", Checksum: map[string]string{"sha1": sha1Hello}},
+			}})
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = string(body)
+		mu.Unlock()
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer mirror.Close()
+
+	err := SyncRepository(context.Background(), SyncOptions{
+		DiffOptions: DiffOptions{
+			RepoURL:    source.URL,
+			RepoName:   "src",
+			RepoType:   "raw",
+			TargetURL:  mirror.URL,
+			TargetRepo: "mirror",
+			TargetAuth: BasicAuth{Username: "dst", Password: "dst-secret"},
+			Workers:    2,
+		},
+		Direction: syncFromRepo,
+		Delete:    true,
+	})
+	if err != nil {
+		t.Fatalf("SyncRepository failed: %v", err)
+	}
+
+	// 3. Отсутствующий и устаревший файлы загружены, лишний удален
+	for _, key := range []string{"PUT /repository/mirror/a.txt", "PUT /repository/mirror/b.txt"} {
+		if got := requests[key]; got != "hello world" {
+			t.Errorf("Expected %s with content 'hello world', got %q", key, got)
+		}
+	}
+	if _, ok := requests["DELETE /service/rest/v1/assets/c-id"]; !ok {
+		t.Error("Expected extra c.txt to be deleted from the mirror")
+	}
+	if len(requests) != 3 {
+		t.Errorf("Expected exactly 3 changes on the mirror, got %v", requests)
+	}
+}
@@ -1,32 +1,45 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 )
 
-func downloadFile(url, destination string, dryRun bool) error {
+// partialSuffix помечает временные файлы незавершенных скачиваний.
+const partialSuffix = ".nexus-partial"
+
+// downloadFile скачивает файл во временный файл рядом с destination и переименовывает
+// его на место только после успешной записи и, если известны контрольные суммы,
+// совпадения хеша. Прерванная загрузка не оставляет файл, похожий на настоящий.
+// Обрыв соединения посреди скачивания повторяется по retryPolicy.
+func downloadFile(ctx context.Context, url, destination string, checksums map[string]string, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+	return retryPolicy.Do(ctx, func() error {
+		return downloadFileOnce(ctx, url, destination, checksums, auth)
+	})
+}
 
-	resp, err := client.Get(url)
+func downloadFileOnce(ctx context.Context, url, destination string, checksums map[string]string, auth Auth) error {
+	resp, err := sendNexusRequest(ctx, "GET", url, "", nil, auth)
 	if err != nil {
-		return fmt.Errorf("failed to download file: %v", err)
+		return fmt.Errorf("failed to download file: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if isRetryableStatus(resp.StatusCode) {
+		return retryableStatusError(resp)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: %s", resp.Status)
+		return statusError("failed to download file", resp)
 	}
 
 	err = os.MkdirAll(filepath.Dir(destination), 0755) // Более безопасные права доступа
@@ -34,16 +47,134 @@ func downloadFile(url, destination string, dryRun bool) error {
 		return fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	out, err := os.Create(destination)
+	out, err := os.CreateTemp(filepath.Dir(destination), "."+filepath.Base(destination)+".*"+partialSuffix)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %v", err)
 	}
-	defer out.Close()
+	tempPath := out.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			out.Close()
+			os.Remove(tempPath)
+		}
+	}()
+
+	var writer io.Writer = out
+	verifier := newChecksumVerifier(checksums)
+	if verifier != nil {
+		writer = io.MultiWriter(out, verifier)
+	}
 
-	_, err = io.Copy(out, resp.Body)
+	written, err := io.Copy(writer, resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %v", err)
+		err = fmt.Errorf("failed to write file: %w", err)
+		if ctx.Err() == nil && isRetryableNetError(err) {
+			return &retryableError{err: err}
+		}
+		return err
+	}
+
+	if verifier != nil {
+		if err := verifier.Verify(); err != nil {
+			return fmt.Errorf("%s: %w", destination, err)
+		}
 	}
 
+	// CreateTemp создает файл с правами 0600, выравниваем их с обычными файлами.
+	if err := out.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to set file mode: %v", err)
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %v", err)
+	}
+	if err := os.Rename(tempPath, destination); err != nil {
+		return fmt.Errorf("failed to move file into place: %v", err)
+	}
+	committed = true
+	requestStatsFrom(ctx).setBytes(written)
+
 	return nil
 }
+
+// removePartialDownloads удаляет временные файлы, оставшиеся от прерванного запуска.
+// В режиме dry-run файлы только перечисляются.
+func removePartialDownloads(dir string, dryRun bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), partialSuffix) {
+			if dryRun {
+				fmt.Printf("[Dry Run] Был бы удален незавершенный файл: %s\n", path)
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove partial download %s: %v", path, err)
+			}
+		}
+		return nil
+	})
+}
+
+// exportStatus описывает, что произошло с ассетом при экспорте.
+type exportStatus int
+
+const (
+	exportNew     exportStatus = iota // файла не было локально
+	exportUpdated                     // локальный файл отличался от ассета
+	exportSkipped                     // локальный файл совпадает с ассетом
+)
+
+func (s exportStatus) String() string {
+	switch s {
+	case exportUpdated:
+		return "updated"
+	case exportSkipped:
+		return "skipped"
+	}
+	return "new"
+}
+
+// localFileStatus сравнивает локальный файл с ассетом из Nexus.
+// Сначала сверяется размер, затем контрольная сумма, а если ее нет — lastModified.
+func localFileStatus(task downloadTask) (exportStatus, error) {
+	info, err := os.Stat(task.FilePath)
+	if os.IsNotExist(err) {
+		return exportNew, nil
+	}
+	if err != nil {
+		return exportNew, fmt.Errorf("failed to stat local file: %v", err)
+	}
+
+	if task.Size > 0 && info.Size() != task.Size {
+		return exportUpdated, nil
+	}
+
+	match, known, err := fileMatchesChecksum(task.FilePath, task.Checksum)
+	if err != nil {
+		return exportUpdated, fmt.Errorf("failed to hash local file: %v", err)
+	}
+	if known {
+		if match {
+			return exportSkipped, nil
+		}
+		return exportUpdated, nil
+	}
+
+	if !task.LastModified.IsZero() {
+		if info.ModTime().Before(task.LastModified) {
+			return exportUpdated, nil
+		}
+		return exportSkipped, nil
+	}
+
+	// Ни контрольной суммы, ни даты изменения: доверяем только совпавшему размеру.
+	if task.Size > 0 {
+		return exportSkipped, nil
+	}
+	return exportUpdated, nil
+}
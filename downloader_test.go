@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chdirTemp переходит во временную директорию на время теста,
+// так как ExportFiles сохраняет файлы относительно текущей директории.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestDownloadFileWithAuth(t *testing.T) {
+	// 1. Сервер отдает файл только авторизованным клиентам
+	server := httptest.NewServer(requireBasicAuth("admin", "secret", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "dir", "file.txt")
+
+	// 2. Без учетных данных получаем 401
+	if err := downloadFile(context.Background(), server.URL+"/file.txt", destination, nil, nil, false); err == nil {
+		t.Error("Expected error without credentials, got nil")
+	}
+
+	// 3. С учетными данными файл скачивается
+	if err := downloadFile(context.Background(), server.URL+"/file.txt", destination, nil, BasicAuth{Username: "admin", Password: "secret"}, false); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	content, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(content) != "hello world" {
+		t.Errorf("Expected content 'hello world', got '%s'", string(content))
+	}
+}
+
+func TestExportFilesWithAuth(t *testing.T) {
+	// 1. Имитируем Nexus: поиск ассетов и скачивание, всё под авторизацией
+	var server *httptest.Server
+	server = httptest.NewServer(requireBasicAuth("admin", "secret", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{
+					{DownloadURL: server.URL + "/repository/test-raw/a.txt", Path: "a.txt"},
+					{DownloadURL: server.URL + "/repository/test-raw/dir/b.txt", Path: "dir/b.txt"},
+				},
+			})
+		case "/repository/test-raw/a.txt":
+			w.Write([]byte("a"))
+		case "/repository/test-raw/dir/b.txt":
+			w.Write([]byte("b"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+
+	// 2. Без учетных данных экспорт должен завершиться ошибкой
+	if err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Workers: 2, QueueSize: 10}); err == nil {
+		t.Error("Expected export to fail without credentials")
+	}
+
+	// 3. С учетными данными все файлы скачиваются
+	if err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Auth: BasicAuth{Username: "admin", Password: "secret"}, Workers: 2, QueueSize: 10}); err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	for path, want := range map[string]string{"a.txt": "a", "dir/b.txt": "b"} {
+		content, err := os.ReadFile(filepath.Join(dir, "test-raw", path))
+		if err != nil {
+			t.Errorf("Failed to read exported file %s: %v", path, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("Expected content '%s' for %s, got '%s'", want, path, string(content))
+		}
+	}
+}
+
+func TestDownloadFileChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	// 1. Совпадающая контрольная сумма — файл сохраняется
+	good := filepath.Join(dir, "good.txt")
+	checksums := map[string]string{
+		"sha1":   "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed",
+		"sha256": "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}
+	if err := downloadFile(context.Background(), server.URL, good, checksums, nil, false); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if _, err := os.Stat(good); err != nil {
+		t.Errorf("Expected file to exist: %v", err)
+	}
+
+	// 2. Несовпадающая контрольная сумма — ошибка, файла на диске нет
+	bad := filepath.Join(dir, "bad.txt")
+	checksums = map[string]string{"sha256": "0000000000000000000000000000000000000000000000000000000000000000"}
+	if err := downloadFile(context.Background(), server.URL, bad, checksums, nil, false); err == nil {
+		t.Error("Expected checksum mismatch error, got nil")
+	}
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Errorf("Expected corrupted file to be removed, stat error: %v", err)
+	}
+}
+
+func TestLocalFileStatus(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set file times: %v", err)
+	}
+	sha1 := "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"
+
+	tests := []struct {
+		name string
+		task downloadTask
+		want exportStatus
+	}{
+		{"missing file", downloadTask{FilePath: filepath.Join(dir, "missing.txt")}, exportNew},
+		{"same checksum", downloadTask{FilePath: path, Size: 11, Checksum: map[string]string{"sha1": sha1}}, exportSkipped},
+		{"other checksum", downloadTask{FilePath: path, Checksum: map[string]string{"sha1": "deadbeef"}}, exportUpdated},
+		{"other size", downloadTask{FilePath: path, Size: 42, Checksum: map[string]string{"sha1": sha1}}, exportUpdated},
+		{"not modified", downloadTask{FilePath: path, LastModified: modTime}, exportSkipped},
+		{"modified later", downloadTask{FilePath: path, LastModified: modTime.Add(time.Hour)}, exportUpdated},
+		{"nothing to compare", downloadTask{FilePath: path}, exportUpdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := localFileStatus(tt.task)
+			if err != nil {
+				t.Fatalf("localFileStatus failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExportFilesIncremental(t *testing.T) {
+	// 1. Сервер считает, сколько раз скачивали каждый файл
+	var mu sync.Mutex
+	downloads := map[string]int{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{
+					{
+						DownloadURL: server.URL + "/repository/test-raw/a.txt",
+						Path:        "a.txt",
+						FileSize:    11,
+						Checksum:    map[string]string{"sha1": "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"},
+					},
+				},
+			})
+			return
+		}
+		mu.Lock()
+		downloads[r.URL.Path]++
+		mu.Unlock()
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	chdirTemp(t)
+
+	// 2. Два инкрементальных запуска подряд: второй не должен ничего скачивать
+	for i := 0; i < 2; i++ {
+		if err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Incremental: true, Workers: 1, QueueSize: 10}); err != nil {
+			t.Fatalf("ExportFiles run %d failed: %v", i+1, err)
+		}
+	}
+	if got := downloads["/repository/test-raw/a.txt"]; got != 1 {
+		t.Errorf("Expected 1 download, got %d", got)
+	}
+}
+
+func TestDownloadFileInterrupted(t *testing.T) {
+	fastRetries(t, 2)
+
+	// 1. Сервер обещает 100 байт, отдает 5 и рвет соединение
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	destination := filepath.Join(dir, "file.txt")
+	if err := downloadFile(context.Background(), server.URL, destination, nil, nil, false); err == nil {
+		t.Fatal("Expected error for truncated download, got nil")
+	}
+
+	// 2. Ни итогового, ни временного файла остаться не должно
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Unexpected file left after failed download: %s", entry.Name())
+	}
+}
+
+func TestRemovePartialDownloads(t *testing.T) {
+	dir := t.TempDir()
+	partial := filepath.Join(dir, "sub", ".file.txt.123"+partialSuffix)
+	complete := filepath.Join(dir, "sub", "file.txt")
+	if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+		t.Fatalf("Failed to create test directories: %v", err)
+	}
+	for _, path := range []string{partial, complete} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	// В режиме dry-run ничего не удаляется
+	if err := removePartialDownloads(dir, true); err != nil {
+		t.Fatalf("removePartialDownloads failed: %v", err)
+	}
+	if _, err := os.Stat(partial); err != nil {
+		t.Errorf("Expected partial file to stay in dry-run: %v", err)
+	}
+
+	if err := removePartialDownloads(dir, false); err != nil {
+		t.Fatalf("removePartialDownloads failed: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file to be removed, stat error: %v", err)
+	}
+	if _, err := os.Stat(complete); err != nil {
+		t.Errorf("Expected complete file to stay: %v", err)
+	}
+}
+
+func TestExportFilesStreamsPages(t *testing.T) {
+	// 1. Вторая страница отдается только после того, как началось скачивание
+	// ассета с первой страницы: без потоковой обработки тест зависнет до таймаута.
+	firstDownloaded := make(chan struct{})
+	var once sync.Once
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service/rest/v1/search/assets" {
+			once.Do(func() { close(firstDownloaded) })
+			w.Write([]byte("data"))
+			return
+		}
+		switch r.URL.Query().Get("continuationToken") {
+		case "":
+			json.NewEncoder(w).Encode(SearchResult{
+				Items:             []Asset{{DownloadURL: server.URL + "/repository/test-raw/a.txt", Path: "a.txt"}},
+				ContinuationToken: "page2",
+			})
+		case "page2":
+			select {
+			case <-firstDownloaded:
+			case <-time.After(5 * time.Second):
+				t.Error("Second page requested before any download started")
+			}
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{{DownloadURL: server.URL + "/repository/test-raw/b.txt", Path: "b.txt"}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+
+	// 2. Оба ассета должны оказаться на диске
+	if err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Workers: 1, QueueSize: 1}); err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "test-raw", name)); err != nil {
+			t.Errorf("Expected %s to be exported: %v", name, err)
+		}
+	}
+
+	// 3. С -workers=0 экспорт работает одним воркером, а не завершается до конца листинга
+	if err := os.RemoveAll(filepath.Join(dir, "test-raw")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Workers: 0, QueueSize: 1}); err != nil {
+		t.Fatalf("ExportFiles with 0 workers failed: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "test-raw", name)); err != nil {
+			t.Errorf("Expected %s to be exported with 0 workers: %v", name, err)
+		}
+	}
+}
+
+func TestExportFilesCancel(t *testing.T) {
+	// 1. Скачивание "зависает": сервер отдает часть данных и ждет, пока клиент не отключится
+	started := make(chan struct{})
+	var once sync.Once
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{
+					{DownloadURL: server.URL + "/repository/test-raw/a.bin", Path: "a.bin"},
+					{DownloadURL: server.URL + "/repository/test-raw/b.bin", Path: "b.bin"},
+				},
+			})
+			return
+		}
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		once.Do(func() { close(started) })
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	// 2. Экспорт завершается ошибкой отмены, на диске не остается ни одного файла
+	err := ExportFiles(ctx, ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Workers: 1, QueueSize: 10})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "test-raw"))
+	if err != nil {
+		t.Fatalf("Failed to read export directory: %v", err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Unexpected file left after cancellation: %s", entry.Name())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Типизированные ошибки Nexus. Проверяются через errors.Is и определяют код выхода,
+// чтобы скрипты отличали, например, неверный пароль от отсутствующего репозитория.
+var (
+	ErrAuth     = errors.New("authentication failed") // 401, 403
+	ErrNotFound = errors.New("not found")             // 404
+	ErrConflict = errors.New("conflict")              // 409 или 400 от политики "Disable redeploy"
+)
+
+// redeployDenied — текст, с которым Nexus отвечает 400 на повторную загрузку
+// в репозиторий с политикой записи "Disable redeploy".
+const redeployDenied = "does not allow updating assets"
+
+// StatusError — неуспешный ответ Nexus. Текст совпадает с прежними сообщениями вида
+// "failed to upload file: 401 Unauthorized", а Unwrap сопоставляет статус с типизированной ошибкой.
+type StatusError struct {
+	Op         string
+	StatusCode int
+	Status     string
+	Body       string // тело ответа, если Nexus объяснил причину
+}
+
+func (e *StatusError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("%s: %s, body: %s", e.Op, e.Status, e.Body)
+	}
+	return fmt.Sprintf("%s: %s", e.Op, e.Status)
+}
+
+func (e *StatusError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusBadRequest:
+		if strings.Contains(e.Body, redeployDenied) {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+// statusError описывает неуспешный ответ resp для операции op.
+func statusError(op string, resp *http.Response) error {
+	return &StatusError{Op: op, StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// statusErrorBody — как statusError, но сохраняет тело ответа с объяснением от Nexus.
+func statusErrorBody(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return &StatusError{Op: op, StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
+}
+
+// FailedItem — файл или ассет, который не удалось обработать.
+type FailedItem struct {
+	Path string
+	Err  error
+}
+
+// PartialFailure возвращается, когда запуск прошел до конца, но часть файлов не обработана.
+// Повторный запуск с -resume имеет смысл именно в этом случае, поэтому у него отдельный код выхода.
+type PartialFailure struct {
+	What   string // "файлов не удалось скачать" — для сообщения "3 из 100 файлов не удалось скачать"
+	Total  int
+	Failed []FailedItem
+}
+
+func (e *PartialFailure) Error() string {
+	return fmt.Sprintf("%d из %d %s", len(e.Failed), e.Total, e.What)
+}
+
+// onlyAuth сообщает, что все файлы упали на авторизации: повтор тогда бесполезен,
+// и запуск завершается как ошибка авторизации, а не как частичный сбой.
+func (e *PartialFailure) onlyAuth() bool {
+	if len(e.Failed) < e.Total {
+		return false
+	}
+	for _, item := range e.Failed {
+		if !errors.Is(item.Err, ErrAuth) {
+			return false
+		}
+	}
+	return len(e.Failed) > 0
+}
+
+// partialFailure возвращает *PartialFailure или nil, если ошибок не было.
+func partialFailure(what string, total int, failed []FailedItem) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return &PartialFailure{What: what, Total: total, Failed: failed}
+}
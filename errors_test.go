@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeImportFiles создает файлы для импорта и возвращает директорию.
+func writeImportFiles(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("hello world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportPartialFailure(t *testing.T) {
+	// 1. conflict.txt уже опубликован, ok.txt загружается
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/conflict.txt") {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	dir := writeImportFiles(t, "ok.txt", "conflict.txt")
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "test-raw",
+		RepoType:  "raw",
+		ImportDir: dir,
+		Workers:   2,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+
+	// 2. Ошибка — PartialFailure со списком упавших файлов и причиной каждого
+	var partial *PartialFailure
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected PartialFailure, got %v", err)
+	}
+	if partial.Total != 2 || len(partial.Failed) != 1 || partial.Failed[0].Path != filepath.Join(dir, "conflict.txt") {
+		t.Fatalf("Unexpected partial failure: %+v", partial)
+	}
+	if !errors.Is(partial.Failed[0].Err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for the item, got %v", partial.Failed[0].Err)
+	}
+	if code := exitCodeFor(err); code != exitPartial {
+		t.Errorf("Expected exit code %d, got %d", exitPartial, code)
+	}
+}
+
+func TestImportAuthFailure(t *testing.T) {
+	// 1. Все загрузки отклонены: повтор не поможет, код выхода — ошибка авторизации
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "test-raw",
+		RepoType:  "raw",
+		ImportDir: writeImportFiles(t, "a.txt", "b.txt"),
+		Workers:   2,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+	if code := exitCodeFor(err); code != exitAuth {
+		t.Errorf("Expected exit code %d, got %d (%v)", exitAuth, code, err)
+	}
+}
+
+func TestExportRepositoryNotFound(t *testing.T) {
+	// 1. Листинг несуществующего репозитория — фатальная ошибка ErrNotFound, а не частичный сбой
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	chdirTemp(t)
+	err := ExportFiles(context.Background(), ExportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "missing",
+		RepoType:  "raw",
+		Workers:   1,
+		QueueSize: 1,
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	var partial *PartialFailure
+	if errors.As(err, &partial) {
+		t.Error("Listing failure must not be reported as partial failure")
+	}
+	if code := exitCodeFor(err); code != exitNotFound {
+		t.Errorf("Expected exit code %d, got %d", exitNotFound, code)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
 // Exporter определяет контракт для экспортеров разных форматов,
 // позволяя обрабатывать специфичные для формата пути.
@@ -9,6 +12,18 @@ type Exporter interface {
 	GetLocalPath(assetPath string) string
 }
 
+// metadataExporter — экспортер, который после скачивания ассетов сохраняет рядом с ними
+// метаданные формата. Такие файлы не являются ассетами, поэтому diff и sync их не сравнивают.
+type metadataExporter interface {
+	// ExportMetadata сохраняет метаданные в exportDir и возвращает число обработанных
+	// документов и те из них, которые сохранить не удалось. Документы, уже сохраненные
+	// прерванным запуском (snapshot), пропускаются, а итог остальных пишется в journal.
+	// Ошибка возвращается, только если шаг прерван отменой ctx.
+	ExportMetadata(ctx context.Context, opts ExportOptions, exportDir string, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error)
+	// IsMetadata сообщает, что файл по пути relPath (через "/") записан ExportMetadata.
+	IsMetadata(relPath string) bool
+}
+
 // GetExporter возвращает нужную реализацию экспортера по типу репозитория.
 func GetExporter(repoType string) Exporter {
 	if u, ok := exporters[repoType]; ok {
@@ -19,7 +34,9 @@ func GetExporter(repoType string) Exporter {
 }
 
 var exporters = map[string]Exporter{
-	"npm": &NpmExporter{},
+	"npm":  &NpmExporter{},
+	"helm": &HelmExporter{},
+	"pypi": &PypiExporter{},
 }
 
 // DefaultExporter - реализация по умолчанию, которая не меняет путь.
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,8 +9,14 @@ import (
 )
 
 type Asset struct {
+	ID          string `json:"id"`
 	DownloadURL string `json:"downloadUrl"`
 	Path        string `json:"path"`
+	// Checksum содержит контрольные суммы ассета по алгоритмам: sha1, sha256, md5, sha512.
+	Checksum map[string]string `json:"checksum"`
+	// FileSize и LastModified отдают Nexus 3.x последних версий, в старых они могут отсутствовать.
+	FileSize     int64     `json:"fileSize"`
+	LastModified time.Time `json:"lastModified"`
 }
 
 type SearchResult struct {
@@ -17,23 +24,20 @@ type SearchResult struct {
 	ContinuationToken string  `json:"continuationToken"`
 }
 
-func fetchAssets(repoURL, repoName, continuationToken string) (SearchResult, error) {
+func fetchAssets(ctx context.Context, repoURL, repoName, continuationToken string, auth Auth) (SearchResult, error) {
 	apiURL := fmt.Sprintf("%s/service/rest/v1/search/assets?repository=%s", repoURL, repoName)
 	if continuationToken != "" {
 		apiURL += "&continuationToken=" + continuationToken
 	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Get(apiURL)
+	resp, err := executeNexusRequest(ctx, "GET", apiURL, "", nil, auth)
 	if err != nil {
-		return SearchResult{}, fmt.Errorf("failed to fetch assets: %v", err)
+		return SearchResult{}, fmt.Errorf("failed to fetch assets: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return SearchResult{}, fmt.Errorf("failed to fetch assets: %s", resp.Status)
+		return SearchResult{}, statusError("failed to fetch assets", resp)
 	}
 
 	var searchResult SearchResult
@@ -44,3 +48,25 @@ func fetchAssets(repoURL, repoName, continuationToken string) (SearchResult, err
 
 	return searchResult, nil
 }
+
+// walkAssets проходит по страницам поиска, начиная со startToken, и передает каждую
+// страницу в fn сразу по мере получения, не накапливая весь список ассетов в памяти.
+// nextToken — токен следующей страницы или пустая строка для последней.
+func walkAssets(ctx context.Context, repoURL, repoName, startToken string, auth Auth, fn func(items []Asset, nextToken string) error) error {
+	continuationToken := startToken
+	for {
+		searchResult, err := fetchAssets(ctx, repoURL, repoName, continuationToken, auth)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(searchResult.Items, searchResult.ContinuationToken); err != nil {
+			return err
+		}
+
+		if searchResult.ContinuationToken == "" {
+			return nil
+		}
+		continuationToken = searchResult.ContinuationToken
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// requireBasicAuth оборачивает обработчик и отвечает 401, если в запросе нет
+// корректного заголовка Authorization — так ведет себя Nexus с выключенным анонимным доступом.
+func requireBasicAuth(username, password string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, p, ok := r.BasicAuth()
+		if !ok || u != username || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func TestFetchAssetsWithAuth(t *testing.T) {
+	// 1. Сервер отдает одну страницу результатов только авторизованным клиентам
+	server := httptest.NewServer(requireBasicAuth("admin", "secret", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service/rest/v1/search/assets" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if repo := r.URL.Query().Get("repository"); repo != "test-raw" {
+			t.Errorf("Expected repository 'test-raw', got '%s'", repo)
+		}
+		json.NewEncoder(w).Encode(SearchResult{
+			Items: []Asset{{DownloadURL: "http://example.com/a.txt", Path: "a.txt"}},
+		})
+	}))
+	defer server.Close()
+
+	// 2. Без учетных данных запрос должен завершиться ошибкой
+	if _, err := fetchAssets(context.Background(), server.URL, "test-raw", "", nil); err == nil {
+		t.Error("Expected error without credentials, got nil")
+	}
+
+	// 3. С учетными данными получаем список ассетов
+	result, err := fetchAssets(context.Background(), server.URL, "test-raw", "", BasicAuth{Username: "admin", Password: "secret"})
+	if err != nil {
+		t.Fatalf("fetchAssets failed: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Path != "a.txt" {
+		t.Errorf("Unexpected search result: %+v", result)
+	}
+}
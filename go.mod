@@ -2,7 +2,10 @@ module nexus-export
 
 go 1.21
 
-require github.com/schollz/progressbar/v3 v3.14.4
+require (
+	github.com/schollz/progressbar/v3 v3.14.4
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db // indirect
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// helmIndexFile — индекс чартов, по которому Helm работает с репозиторием.
+const helmIndexFile = "index.yaml"
+
+// helmVersion — версия чарта в формате SemVer 2, которого требует Helm.
+var helmVersion = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,2}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// helmChart — Chart.yaml, прочитанный из архива чарта.
+type helmChart struct {
+	Name       string
+	Version    string
+	APIVersion string
+	Metadata   map[string]any // все поля Chart.yaml, они же попадают в index.yaml
+}
+
+// readHelmChart читает и проверяет <chart>/Chart.yaml из архива чарта.
+// Файлы, которые не являются чартами, например npm-пакеты, возвращают *notPackageError.
+func readHelmChart(ctx context.Context, artifact Artifact) (helmChart, error) {
+	body, err := artifact.Open(ctx)
+	if err != nil {
+		return helmChart{}, err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return helmChart{}, notPackage("not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return helmChart{}, notPackage("no <chart>/Chart.yaml in the archive")
+		}
+		if err != nil {
+			return helmChart{}, notPackage("broken tar archive: %v", err)
+		}
+		// Chart.yaml зависимостей лежат глубже, в <chart>/charts/; нужен только корневой.
+		parts := strings.Split(strings.TrimPrefix(header.Name, "./"), "/")
+		if len(parts) != 2 || parts[1] != "Chart.yaml" {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, 1<<20))
+		if err != nil {
+			return helmChart{}, notPackage("broken tar archive: %v", err)
+		}
+		// Поля читаются в строки отдельно: иначе версия 1.10 превратилась бы в число 1.1.
+		var fields struct {
+			Name       string `yaml:"name"`
+			Version    string `yaml:"version"`
+			APIVersion string `yaml:"apiVersion"`
+		}
+		var metadata map[string]any
+		if err := yaml.Unmarshal(data, &fields); err != nil {
+			return helmChart{}, notPackage("%s is not valid YAML: %v", header.Name, err)
+		}
+		if err := yaml.Unmarshal(data, &metadata); err != nil {
+			return helmChart{}, notPackage("%s is not valid YAML: %v", header.Name, err)
+		}
+		if metadata == nil {
+			metadata = map[string]any{}
+		}
+		metadata["version"] = fields.Version
+		chart := helmChart{Name: fields.Name, Version: fields.Version, APIVersion: fields.APIVersion, Metadata: metadata}
+		if chart.Name == "" {
+			return helmChart{}, notPackage("%s has no name", header.Name)
+		}
+		if chart.APIVersion != "v1" && chart.APIVersion != "v2" {
+			return helmChart{}, notPackage("unsupported apiVersion %q in %s, use v1 or v2", chart.APIVersion, header.Name)
+		}
+		if !helmVersion.MatchString(chart.Version) {
+			return helmChart{}, notPackage("invalid version %q of chart %s", chart.Version, chart.Name)
+		}
+		return chart, nil
+	}
+}
+
+// HelmExporter сохраняет ассеты как есть и строит по скачанным чартам локальный index.yaml.
+type HelmExporter struct {
+	DefaultExporter
+}
+
+// IsMetadata сообщает, что файл — index.yaml, который экспорт строит сам.
+func (e *HelmExporter) IsMetadata(relPath string) bool {
+	return relPath == helmIndexFile
+}
+
+// helmIndex — index.yaml статического Helm-репозитория.
+type helmIndex struct {
+	APIVersion string                      `yaml:"apiVersion"`
+	Entries    map[string][]map[string]any `yaml:"entries"`
+	Generated  string                      `yaml:"generated"`
+}
+
+// ExportMetadata строит index.yaml по чартам в exportDir, чтобы каталог можно было
+// сразу раздавать как статический Helm-репозиторий. Ссылки на чарты относительные.
+func (e *HelmExporter) ExportMetadata(ctx context.Context, opts ExportOptions, exportDir string, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error) {
+	if opts.DryRun {
+		return 0, nil, nil
+	}
+	started := time.Now()
+	indexPath := filepath.Join(exportDir, helmIndexFile)
+	charts, err := writeHelmIndex(ctx, exportDir, indexPath)
+	item := ReportItem{Path: indexPath, Result: exportNew.String(), DurationMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		item.Result, item.Error = reportResultFailed, err.Error()
+		opts.Report.Add(item)
+		fmt.Fprintf(os.Stderr, "Ошибка построения %s: %v\n", helmIndexFile, err)
+		return 1, []FailedItem{{Path: indexPath, Err: err}}, nil
+	}
+	opts.Report.Add(item)
+	fmt.Printf("Построен %s: чартов %d\n", indexPath, charts)
+	return 1, nil, nil
+}
+
+// writeHelmIndex записывает index.yaml по всем чартам в dir и возвращает их число.
+func writeHelmIndex(ctx context.Context, dir, indexPath string) (int, error) {
+	index := helmIndex{APIVersion: "v1", Entries: map[string][]map[string]any{}, Generated: time.Now().UTC().Format(time.RFC3339Nano)}
+	charts := 0
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(filePath, ".tgz") {
+			return nil
+		}
+		artifact, err := localArtifact(filePath, dir)
+		if err != nil {
+			return err
+		}
+		chart, err := readHelmChart(ctx, artifact)
+		var skip *notPackageError
+		if errors.As(err, &skip) {
+			fmt.Fprintf(os.Stderr, "Пропущен %s: %s\n", artifact.Path, skip.Reason)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		digest, err := fileSHA256(filePath)
+		if err != nil {
+			return err
+		}
+
+		entry := make(map[string]any, len(chart.Metadata)+3)
+		for key, value := range chart.Metadata {
+			entry[key] = value
+		}
+		entry["created"] = info.ModTime().UTC().Format(time.RFC3339Nano)
+		entry["digest"] = digest
+		entry["urls"] = []string{artifact.Path}
+		index.Entries[chart.Name] = append(index.Entries[chart.Name], entry)
+		charts++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Как и helm repo index, новые версии идут первыми.
+	for _, entries := range index.Entries {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return compareVersions(fmt.Sprint(entries[i]["version"]), fmt.Sprint(entries[j]["version"])) > 0
+		})
+	}
+
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s: %w", helmIndexFile, err)
+	}
+	if err := os.WriteFile(indexPath, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", helmIndexFile, err)
+	}
+	return charts, nil
+}
+
+// fileSHA256 считает sha256 файла.
+func fileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// compareVersions сравнивает версии по частям, разделенным точками и дефисами:
+// числовые части — как числа, остальные — как строки. Версия без пре-релиза
+// старше версии с ним: 1.0.0 > 1.0.0-rc.1.
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	// Метаданные сборки после "+" в сравнении не участвуют.
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aRelease, aPre, _ := strings.Cut(a, "-")
+	bRelease, bPre, _ := strings.Cut(b, "-")
+	if c := compareVersionParts(aRelease, bRelease); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareVersionParts(aPre, bPre)
+}
+
+func compareVersionParts(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			return -1
+		}
+		if i >= len(bParts) {
+			return 1
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr == nil) != (bErr == nil):
+			// Числовая часть младше буквенной, как в SemVer.
+			if aErr == nil {
+				return -1
+			}
+			return 1
+		case aErr != nil && aParts[i] != bParts[i]:
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+	return 0
+}
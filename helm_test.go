@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestReadHelmChart(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		files  map[string]string
+		want   string
+		reason string
+	}{
+		{"chart", map[string]string{
+			"mychart/Chart.yaml":             "apiVersion: v2\nname: mychart\nversion: 1.10\n",
+			"mychart/charts/dep/Chart.yaml":  "apiVersion: v2\nname: dep\nversion: 0.1.0\n",
+			"mychart/templates/service.yaml": "kind: Service\n",
+		}, "mychart@1.10", ""},
+		{"npm", map[string]string{"package/package.json": `{"name":"pkg","version":"1.0.0"}`}, "", "no <chart>/Chart.yaml"},
+		{"api", map[string]string{"mychart/Chart.yaml": "apiVersion: v3\nname: mychart\nversion: 1.0.0\n"}, "", "unsupported apiVersion"},
+		{"version", map[string]string{"mychart/Chart.yaml": "apiVersion: v2\nname: mychart\nversion: latest\n"}, "", "invalid version"},
+	}
+	for _, tt := range tests {
+		tgz := filepath.Join(dir, tt.name+".tgz")
+		writeTarball(t, tgz, tt.files)
+		info, err := (&HelmUploader{}).Inspect(context.Background(), mustLocalArtifact(t, tgz, dir))
+		var skip *notPackageError
+		switch {
+		case tt.reason == "" && (err != nil || info.String() != tt.want):
+			t.Errorf("%s: expected %s, got %s (%v)", tt.name, tt.want, info, err)
+		case tt.reason != "" && (!errors.As(err, &skip) || !strings.Contains(skip.Reason, tt.reason)):
+			t.Errorf("%s: expected skip reason %q, got %v", tt.name, tt.reason, err)
+		}
+	}
+}
+
+func TestExportHelmIndex(t *testing.T) {
+	// 1. В репозитории две версии чарта и index.yaml самого Nexus
+	chartDir := t.TempDir()
+	charts := map[string][]byte{}
+	for _, version := range []string{"0.9.0", "0.10.0"} {
+		tgz := filepath.Join(chartDir, "mychart-"+version+".tgz")
+		writeTarball(t, tgz, map[string]string{"mychart/Chart.yaml": "apiVersion: v2\nname: mychart\nappVersion: \"2.0\"\nversion: " + version + "\n"})
+		data, err := os.ReadFile(tgz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		charts["/repository/helm-hosted/mychart-"+version+".tgz"] = data
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: server.URL + "/repository/helm-hosted/mychart-0.9.0.tgz", Path: "mychart-0.9.0.tgz"},
+				{DownloadURL: server.URL + "/repository/helm-hosted/mychart-0.10.0.tgz", Path: "mychart-0.10.0.tgz"},
+				{DownloadURL: server.URL + "/repository/helm-hosted/index.yaml", Path: "index.yaml"},
+			}})
+			return
+		}
+		if data, ok := charts[r.URL.Path]; ok {
+			w.Write(data)
+			return
+		}
+		w.Write([]byte("apiVersion: v1\nentries: {}\n"))
+	}))
+	defer server.Close()
+
+	// 2. После экспорта index.yaml построен по скачанным чартам с относительными ссылками
+	dir := chdirTemp(t)
+	err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "helm-hosted", RepoType: "helm", Workers: 2, QueueSize: 10})
+	if err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "helm-hosted", helmIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index struct {
+		APIVersion string `yaml:"apiVersion"`
+		Entries    map[string][]struct {
+			Version    string   `yaml:"version"`
+			AppVersion string   `yaml:"appVersion"`
+			Digest     string   `yaml:"digest"`
+			URLs       []string `yaml:"urls"`
+		} `yaml:"entries"`
+	}
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Invalid index.yaml: %v", err)
+	}
+	entries := index.Entries["mychart"]
+	if index.APIVersion != "v1" || len(entries) != 2 {
+		t.Fatalf("Unexpected index.yaml:\n%s", data)
+	}
+	// Новая версия первой, сравнение по числам, а не по строкам
+	if entries[0].Version != "0.10.0" || entries[0].URLs[0] != "mychart-0.10.0.tgz" || entries[0].AppVersion != "2.0" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Digest != fmt.Sprintf("%x", sha256.Sum256(charts["/repository/helm-hosted/mychart-0.9.0.tgz"])) {
+		t.Errorf("Unexpected digest %s", entries[1].Digest)
+	}
+}
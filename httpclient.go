@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTPClientConfig задает параметры общего HTTP-клиента.
+// Общего таймаута на запрос нет: загрузка многогигабайтного артефакта может идти долго,
+// поэтому ограничиваются только установка соединения, ожидание заголовков ответа и простой.
+type HTTPClientConfig struct {
+	ConnectTimeout        time.Duration // TCP-подключение и TLS-рукопожатие
+	ResponseHeaderTimeout time.Duration // ожидание заголовков ответа после отправки запроса
+	IdleConnTimeout       time.Duration // сколько простаивающее соединение хранится в пуле
+	MaxIdleConnsPerHost   int           // размер пула соединений, обычно равен числу воркеров
+
+	CACertFile         string // PEM-бандл с дополнительными корневыми сертификатами
+	InsecureSkipVerify bool   // не проверять сертификат сервера (только для тестовых стендов)
+	ClientCertFile     string // клиентский сертификат для mTLS
+	ClientKeyFile      string // ключ клиентского сертификата
+}
+
+var defaultHTTPClientConfig = HTTPClientConfig{
+	ConnectTimeout:        30 * time.Second,
+	ResponseHeaderTimeout: 5 * time.Minute,
+	IdleConnTimeout:       90 * time.Second,
+	MaxIdleConnsPerHost:   10,
+}
+
+// httpClient общий для скачивания, листинга и загрузки, чтобы воркеры переиспользовали
+// соединения. main заменяет его клиентом, собранным из флагов.
+var httpClient = &http.Client{Transport: newTransport(defaultHTTPClientConfig)}
+
+// newHTTPClient собирает клиент с пулом соединений, прокси из окружения
+// (HTTPS_PROXY, HTTP_PROXY, NO_PROXY) и настройками TLS.
+func newHTTPClient(cfg HTTPClientConfig) (*http.Client, error) {
+	transport := newTransport(cfg)
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+func newTransport(cfg HTTPClientConfig) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   cfg.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		ExpectContinueTimeout: 1 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+func newTLSConfig(cfg HTTPClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" || cfg.ClientKeyFile != "" {
+		if cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+			return nil, fmt.Errorf("both client certificate and key are required for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useHTTPClient подменяет общий клиент на время теста.
+func useHTTPClient(t *testing.T, cfg HTTPClientConfig) {
+	t.Helper()
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatalf("newHTTPClient failed: %v", err)
+	}
+	saved := httpClient
+	httpClient = client
+	t.Cleanup(func() { httpClient = saved })
+}
+
+func TestHTTPClientCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 1. Самоподписанный сертификат сервера не проходит проверку по умолчанию
+	useHTTPClient(t, defaultHTTPClientConfig)
+	if _, err := executeNexusRequest(context.Background(), "GET", server.URL, "", nil, nil); err == nil {
+		t.Error("Expected TLS verification error, got nil")
+	}
+
+	// 2. С сертификатом сервера в CA-бандле запрос проходит
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+	cfg := defaultHTTPClientConfig
+	cfg.CACertFile = caFile
+	useHTTPClient(t, cfg)
+
+	resp, err := executeNexusRequest(context.Background(), "GET", server.URL, "", nil, nil)
+	if err != nil {
+		t.Fatalf("Request with custom CA failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// 3. -insecure отключает проверку
+	cfg = defaultHTTPClientConfig
+	cfg.InsecureSkipVerify = true
+	useHTTPClient(t, cfg)
+
+	resp, err = executeNexusRequest(context.Background(), "GET", server.URL, "", nil, nil)
+	if err != nil {
+		t.Fatalf("Insecure request failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewHTTPClientInvalidConfig(t *testing.T) {
+	cfg := defaultHTTPClientConfig
+	cfg.ClientCertFile = "client.pem"
+	if _, err := newHTTPClient(cfg); err == nil {
+		t.Error("Expected error for client certificate without key")
+	}
+
+	cfg = defaultHTTPClientConfig
+	cfg.CACertFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := newHTTPClient(cfg); err == nil {
+		t.Error("Expected error for missing CA bundle")
+	}
+}
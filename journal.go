@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Состояния задачи в журнале.
+const (
+	journalPending = "pending"
+	journalDone    = "done"
+	journalFailed  = "failed"
+)
+
+// Виды записей журнала.
+const (
+	journalKindRun      = "run"      // заголовок: для какого запуска ведется журнал
+	journalKindTask     = "task"     // изменение состояния задачи
+	journalKindPage     = "page"     // прочитана страница листинга, Token — следующая страница
+	journalKindListed   = "listed"   // листинг завершен, все задачи записаны
+	journalKindMetadata = "metadata" // документ метаданных формата, обрабатывается после файлов
+)
+
+// journalEntry — одна строка журнала в формате JSONL.
+type journalEntry struct {
+	Kind  string          `json:"kind"`
+	Key   string          `json:"key,omitempty"`
+	State string          `json:"state,omitempty"`
+	Error string          `json:"error,omitempty"`
+	Token string          `json:"token,omitempty"`
+	Run   *journalRun     `json:"run,omitempty"`
+	Task  json.RawMessage `json:"task,omitempty"`
+	Time  time.Time       `json:"time"`
+}
+
+// journalRun описывает запуск, чтобы -resume не продолжил чужой журнал.
+type journalRun struct {
+	Action   string `json:"action"`
+	RepoURL  string `json:"repoUrl"`
+	RepoName string `json:"repoName"`
+	Source   string `json:"source,omitempty"`
+}
+
+// journalSnapshot — состояние, восстановленное из журнала прерванного запуска.
+type journalSnapshot struct {
+	Tasks     map[string]*journalEntry // последняя запись по каждому ключу
+	Order     []string                 // ключи в порядке появления
+	NextToken string                   // continuationToken первой непрочитанной страницы
+	Listed    bool                     // листинг был завершен
+	Metadata  map[string]string        // состояние документов метаданных по ключу
+}
+
+// Pending возвращает записи задач, которые не были успешно выполнены, в исходном порядке.
+func (s *journalSnapshot) Pending() []*journalEntry {
+	var pending []*journalEntry
+	for _, key := range s.Order {
+		if entry := s.Tasks[key]; entry.State != journalDone {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// Done сообщает, была ли задача успешно выполнена в прерванном запуске.
+func (s *journalSnapshot) Done(key string) bool {
+	entry, ok := s.Tasks[key]
+	return ok && entry.State == journalDone
+}
+
+// MetadataDone сообщает, был ли документ метаданных обработан в прерванном запуске.
+// У nil-состояния (запуск не продолжается) обработанных документов нет.
+func (s *journalSnapshot) MetadataDone(key string) bool {
+	return s != nil && s.Metadata[key] == journalDone
+}
+
+// Known сообщает, записана ли задача в журнал.
+func (s *journalSnapshot) Known(key string) bool {
+	_, ok := s.Tasks[key]
+	return ok
+}
+
+// Journal дописывает состояния задач в файл. Каждая запись — отдельный write,
+// поэтому после падения процесса в журнале остаются все завершенные записи.
+// Методы безопасны для вызова из нескольких воркеров; у nil-журнала они ничего не делают.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// openJournal открывает журнал запуска. При resume читает прежние записи
+// и продолжает дописывать в тот же файл, иначе начинает журнал заново.
+func openJournal(path string, run journalRun, resume bool) (*Journal, *journalSnapshot, error) {
+	var snapshot *journalSnapshot
+	if resume {
+		var err error
+		snapshot, err = readJournal(path, run)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if snapshot != nil {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	journal := &Journal{file: file, path: path}
+	if snapshot == nil {
+		if err := journal.write(journalEntry{Kind: journalKindRun, Run: &run}); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	return journal, snapshot, nil
+}
+
+// readJournal восстанавливает состояние из журнала. Отсутствующий журнал
+// означает, что продолжать нечего, и возвращает nil без ошибки.
+func readJournal(path string, run journalRun) (*journalSnapshot, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	snapshot := &journalSnapshot{Tasks: map[string]*journalEntry{}, Metadata: map[string]string{}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Последняя строка могла оборваться при падении — ее просто пропускаем.
+			continue
+		}
+
+		switch entry.Kind {
+		case journalKindRun:
+			if entry.Run == nil || *entry.Run != run {
+				return nil, fmt.Errorf("journal %s belongs to another run (%+v), remove it or drop -resume", path, entry.Run)
+			}
+		case journalKindTask:
+			prev, ok := snapshot.Tasks[entry.Key]
+			if !ok {
+				snapshot.Order = append(snapshot.Order, entry.Key)
+			} else if entry.Task == nil {
+				// Записи о завершении не повторяют данные задачи.
+				entry.Task = prev.Task
+			}
+			e := entry
+			snapshot.Tasks[entry.Key] = &e
+		case journalKindPage:
+			snapshot.NextToken = entry.Token
+		case journalKindListed:
+			snapshot.Listed = true
+		case journalKindMetadata:
+			snapshot.Metadata[entry.Key] = entry.State
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return snapshot, nil
+}
+
+// AddTask записывает новую задачу в состоянии pending вместе с данными для ее повтора.
+func (j *Journal) AddTask(key string, task interface{}) error {
+	if j == nil {
+		return nil
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal task: %w", err)
+	}
+	return j.write(journalEntry{Kind: journalKindTask, Key: key, State: journalPending, Task: data})
+}
+
+// Finish записывает итог задачи: done или failed с текстом ошибки.
+func (j *Journal) Finish(key string, taskErr error) error {
+	if j == nil {
+		return nil
+	}
+	entry := journalEntry{Kind: journalKindTask, Key: key, State: journalDone}
+	if taskErr != nil {
+		entry.State = journalFailed
+		entry.Error = taskErr.Error()
+	}
+	return j.write(entry)
+}
+
+// AddMetadata записывает документ метаданных в состоянии pending. Такие документы
+// обрабатывает шаг метаданных после файлов, поэтому в очередь задач они не попадают.
+func (j *Journal) AddMetadata(key string) error {
+	if j == nil {
+		return nil
+	}
+	return j.write(journalEntry{Kind: journalKindMetadata, Key: key, State: journalPending})
+}
+
+// FinishMetadata записывает итог документа метаданных: done или failed с текстом ошибки.
+func (j *Journal) FinishMetadata(key string, taskErr error) error {
+	if j == nil {
+		return nil
+	}
+	entry := journalEntry{Kind: journalKindMetadata, Key: key, State: journalDone}
+	if taskErr != nil {
+		entry.State = journalFailed
+		entry.Error = taskErr.Error()
+	}
+	return j.write(entry)
+}
+
+// Page отмечает, что листинг дошел до страницы с токеном nextToken.
+func (j *Journal) Page(nextToken string) error {
+	if j == nil {
+		return nil
+	}
+	return j.write(journalEntry{Kind: journalKindPage, Token: nextToken})
+}
+
+// Listed отмечает, что все задачи запуска записаны в журнал.
+func (j *Journal) Listed() error {
+	if j == nil {
+		return nil
+	}
+	return j.write(journalEntry{Kind: journalKindListed})
+}
+
+// Close закрывает журнал. При remove файл удаляется: запуск завершился полностью
+// и продолжать нечего.
+func (j *Journal) Close(remove bool) error {
+	if j == nil {
+		return nil
+	}
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	if remove {
+		return os.Remove(j.path)
+	}
+	return nil
+}
+
+func (j *Journal) write(entry journalEntry) error {
+	entry.Time = time.Now().UTC()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestExportFilesResume(t *testing.T) {
+	// 1. Первый запуск: b.txt отдает 404, листинг завершен
+	var mu sync.Mutex
+	requests := map[string]int{}
+	failB := true
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		fail := failB
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{
+					{DownloadURL: server.URL + "/repository/test-raw/a.txt", Path: "a.txt"},
+					{DownloadURL: server.URL + "/repository/test-raw/b.txt", Path: "b.txt"},
+				},
+			})
+		case "/repository/test-raw/b.txt":
+			if fail {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte("b"))
+		default:
+			w.Write([]byte("a"))
+		}
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+	opts := ExportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", Workers: 1, QueueSize: 10}
+	if err := ExportFiles(context.Background(), opts); err == nil {
+		t.Fatal("Expected first export to fail")
+	}
+	journalPath := filepath.Join(dir, defaultJournalPath("export", "test-raw"))
+	if _, err := os.Stat(journalPath); err != nil {
+		t.Fatalf("Expected journal to be kept after a failed run: %v", err)
+	}
+
+	// 2. Продолжение: без повторного листинга скачивается только b.txt
+	mu.Lock()
+	failB = false
+	requests = map[string]int{}
+	mu.Unlock()
+
+	opts.Resume = true
+	if err := ExportFiles(context.Background(), opts); err != nil {
+		t.Fatalf("Resumed export failed: %v", err)
+	}
+	if requests["/service/rest/v1/search/assets"] != 0 {
+		t.Error("Expected resumed export not to list assets again")
+	}
+	if requests["/repository/test-raw/a.txt"] != 0 {
+		t.Error("Expected completed asset not to be downloaded again")
+	}
+	if requests["/repository/test-raw/b.txt"] != 1 {
+		t.Errorf("Expected failed asset to be downloaded once, got %d", requests["/repository/test-raw/b.txt"])
+	}
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Errorf("Expected journal to be removed after a complete run, stat error: %v", err)
+	}
+}
+
+func TestImportFilesResume(t *testing.T) {
+	// 1. Первый запуск: загрузка b.txt отклоняется
+	var mu sync.Mutex
+	uploads := map[string]int{}
+	failB := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		uploads[r.URL.Path]++
+		if r.URL.Path == "/repository/test-raw/b.txt" && failB {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+	importDir := filepath.Join(dir, "import")
+	if err := os.MkdirAll(importDir, 0755); err != nil {
+		t.Fatalf("Failed to create import directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(importDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	opts := ImportOptions{RepoURL: server.URL, RepoName: "test-raw", RepoType: "raw", ImportDir: importDir, Workers: 1}
+	if err := ImportFiles(context.Background(), opts); err == nil {
+		t.Fatal("Expected first import to fail")
+	}
+
+	// 2. Новый файл после завершенного обхода не подхватывается: список берется из журнала
+	if err := os.WriteFile(filepath.Join(importDir, "c.txt"), []byte("c"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	mu.Lock()
+	failB = false
+	uploads = map[string]int{}
+	mu.Unlock()
+
+	opts.Resume = true
+	if err := ImportFiles(context.Background(), opts); err != nil {
+		t.Fatalf("Resumed import failed: %v", err)
+	}
+	if len(uploads) != 1 || uploads["/repository/test-raw/b.txt"] != 1 {
+		t.Errorf("Expected only b.txt to be uploaded again, got %v", uploads)
+	}
+}
+
+func TestOpenJournalRejectsOtherRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.journal.jsonl")
+	journal, _, err := openJournal(path, journalRun{Action: "export", RepoURL: "http://a", RepoName: "one"}, false)
+	if err != nil {
+		t.Fatalf("openJournal failed: %v", err)
+	}
+	journal.Close(false)
+
+	if _, _, err := openJournal(path, journalRun{Action: "export", RepoURL: "http://a", RepoName: "two"}, true); err == nil {
+		t.Error("Expected error when resuming a journal of another run")
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// mavenCoordinates — координаты файла в раскладке Maven:
+// group/artifact/version/artifact-version[-classifier].extension.
+type mavenCoordinates struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+	Classifier string
+	Extension  string // "jar", "pom", "jar.asc" — все после первой точки
+	FileName   string // имя файла в репозитории; у уникальных SNAPSHOT содержит метку времени
+}
+
+// Path возвращает путь файла в репозитории Maven.
+func (c mavenCoordinates) Path() string {
+	return strings.Join([]string{strings.ReplaceAll(c.GroupID, ".", "/"), c.ArtifactID, c.Version, c.FileName}, "/")
+}
+
+// mavenChecksumExtensions — расширения файлов с контрольными суммами, которые лежат рядом с артефактом.
+var mavenChecksumExtensions = []string{"md5", "sha1", "sha256", "sha512"}
+
+// mavenGeneratedChecksums — контрольные суммы, без которых Maven-клиенты выдают предупреждение.
+// Если их нет рядом с локальным файлом, они считаются и загружаются вместе с ним.
+var mavenGeneratedChecksums = []string{"md5", "sha1"}
+
+// mavenArtifactExtensions — расширения артефактов, координаты которых ищутся
+// в POM, даже если файл лежит вне раскладки Maven, например в плоской lib/.
+var mavenArtifactExtensions = []string{".jar", ".pom", ".war", ".ear", ".aar", ".module"}
+
+// mavenArchiveExtensions — zip-архивы, внутри которых может быть META-INF/maven/**/pom.properties.
+var mavenArchiveExtensions = []string{".jar", ".war", ".ear"}
+
+// mavenPrimaryExtension — расширение артефакта без подписи и контрольных сумм:
+// одно слово (jar, pom, module) или архив tar (tar.gz). Так в раскладку не попадают
+// служебные файлы вроде app-1.0.jar.lastUpdated.
+var mavenPrimaryExtension = regexp.MustCompile(`^([A-Za-z0-9]+|tar\.[A-Za-z0-9]+)$`)
+
+// mavenSnapshotTimestamp — метка уникальной SNAPSHOT-версии: 1.0-20240115.103000-3.
+var mavenSnapshotTimestamp = regexp.MustCompile(`^[0-9]{8}\.[0-9]{6}-[0-9]+`)
+
+// parseMavenPath разбирает путь в раскладке Maven. Имя файла должно начинаться
+// с artifactId-version; для SNAPSHOT вместо version допускается метка времени.
+func parseMavenPath(filePath string) (mavenCoordinates, bool) {
+	parts := strings.Split(filepath.ToSlash(filePath), "/")
+	if len(parts) < 4 {
+		return mavenCoordinates{}, false
+	}
+	coords := mavenCoordinates{
+		GroupID:    strings.Join(parts[:len(parts)-3], "."),
+		ArtifactID: parts[len(parts)-3],
+		Version:    parts[len(parts)-2],
+		FileName:   parts[len(parts)-1],
+	}
+	var ok bool
+	coords.Classifier, coords.Extension, ok = splitMavenFileName(coords.FileName, coords.ArtifactID, coords.Version)
+	if !ok {
+		return mavenCoordinates{}, false
+	}
+	return coords, true
+}
+
+// splitMavenFileName выделяет классификатор и расширение из имени файла artifactId-version[-classifier].extension.
+func splitMavenFileName(fileName, artifactID, version string) (classifier, extension string, ok bool) {
+	rest, ok := strings.CutPrefix(fileName, artifactID+"-")
+	if !ok {
+		return "", "", false
+	}
+	if after, ok := strings.CutPrefix(rest, version); ok {
+		rest = after
+	} else if base, ok := strings.CutSuffix(version, "SNAPSHOT"); ok && strings.HasPrefix(rest, base) {
+		stamp := mavenSnapshotTimestamp.FindString(rest[len(base):])
+		if stamp == "" {
+			return "", "", false
+		}
+		rest = rest[len(base)+len(stamp):]
+	} else {
+		return "", "", false
+	}
+
+	if after, ok := strings.CutPrefix(rest, "-"); ok {
+		classifier, rest, _ = strings.Cut(after, ".")
+		rest = "." + rest
+	}
+	extension, ok = strings.CutPrefix(rest, ".")
+	if !ok || extension == "" {
+		return "", "", false
+	}
+	if primary, _ := cutMavenSidecar(extension); !mavenPrimaryExtension.MatchString(strings.TrimPrefix(primary, ".")) {
+		return "", "", false
+	}
+	return classifier, extension, true
+}
+
+// isMavenLocalRepositoryFile сообщает, что файл — служебный файл локального репозитория
+// ~/.m2: отметки неудачных скачиваний, сведения о происхождении артефактов и метаданные
+// удаленных репозиториев. В репозиторий Nexus они не загружаются.
+func isMavenLocalRepositoryFile(filePath string) bool {
+	name := path.Base(filepath.ToSlash(filePath))
+	primary, _ := cutMavenSidecar(name)
+	switch {
+	case strings.HasSuffix(name, ".lastUpdated"),
+		name == "_remote.repositories",
+		name == "resolver-status.properties":
+		return true
+	case strings.HasPrefix(primary, "maven-metadata-") && strings.HasSuffix(primary, ".xml"):
+		return true // maven-metadata-central.xml
+	}
+	return false
+}
+
+// isMavenMetadata сообщает, что файл — maven-metadata.xml или его контрольная сумма.
+func isMavenMetadata(filePath string) bool {
+	primary, _ := cutMavenSidecar(path.Base(filepath.ToSlash(filePath)))
+	return primary == "maven-metadata.xml"
+}
+
+// isMavenChecksum сообщает, что файл — контрольная сумма другого файла.
+func isMavenChecksum(filePath string) bool {
+	for _, ext := range mavenChecksumExtensions {
+		if strings.HasSuffix(filePath, "."+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMavenLayoutFile сообщает, что файл лежит в раскладке Maven: артефакт с любым
+// классификатором и расширением, подпись, контрольная сумма или maven-metadata.xml.
+func isMavenLayoutFile(filePath string) bool {
+	if isMavenLocalRepositoryFile(filePath) {
+		return false
+	}
+	if isMavenMetadata(filePath) {
+		return len(strings.Split(filepath.ToSlash(filePath), "/")) >= 3
+	}
+	_, ok := parseMavenPath(filePath)
+	return ok
+}
+
+// isMavenArtifactFile сообщает, что файл — артефакт Maven или его подпись
+// и контрольная сумма, где бы он ни лежал.
+func isMavenArtifactFile(filePath string) bool {
+	if isMavenLocalRepositoryFile(filePath) {
+		return false
+	}
+	primary, _ := cutMavenSidecar(filePath)
+	return slices.Contains(mavenArtifactExtensions, strings.ToLower(filepath.Ext(primary)))
+}
+
+// cutMavenSidecar отделяет от имени файла суффиксы подписи и контрольных сумм:
+// app-1.0.jar.asc.sha1 -> app-1.0.jar, .asc.sha1.
+func cutMavenSidecar(filePath string) (primary, suffix string) {
+	primary = filePath
+	for {
+		ext := filepath.Ext(primary)
+		if ext != ".asc" && !slices.Contains(mavenChecksumExtensions, strings.TrimPrefix(ext, ".")) {
+			return primary, filePath[len(primary):]
+		}
+		primary = strings.TrimSuffix(primary, ext)
+	}
+}
+
+// mavenGAV — groupId, artifactId и version из POM или pom.properties.
+type mavenGAV struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+}
+
+// coordinates возвращает координаты файла fileName с суффиксом suffix (.sha1, .asc).
+// Если имя файла не начинается с artifactId-version, например у переименованного
+// jar, файл загружается под каноническим именем без классификатора.
+func (g mavenGAV) coordinates(fileName, suffix string) mavenCoordinates {
+	coords := mavenCoordinates{GroupID: g.GroupID, ArtifactID: g.ArtifactID, Version: g.Version, FileName: fileName}
+	var ok bool
+	coords.Classifier, coords.Extension, ok = splitMavenFileName(fileName, g.ArtifactID, g.Version)
+	if !ok {
+		coords.Classifier = ""
+		coords.Extension = strings.TrimPrefix(filepath.Ext(fileName), ".")
+		coords.FileName = g.ArtifactID + "-" + g.Version + "." + coords.Extension
+	}
+	coords.Extension += suffix
+	coords.FileName += suffix
+	return coords
+}
+
+// resolveMavenCoordinates определяет координаты артефакта. Для локального файла они
+// читаются из POM рядом с ним или из META-INF/maven/**/pom.properties внутри jar,
+// поэтому файл попадает по своему GAV, где бы он ни лежал. Раскладка каталогов
+// используется, только если POM не найден, и для артефактов из другого репозитория.
+func resolveMavenCoordinates(artifact Artifact) (mavenCoordinates, error) {
+	if artifact.LocalPath == "" {
+		if coords, ok := parseMavenPath(artifact.Path); ok {
+			return coords, nil
+		}
+		return mavenCoordinates{}, fmt.Errorf("invalid file path for Maven repository: %s", artifact.Path)
+	}
+
+	primary, suffix := cutMavenSidecar(artifact.LocalPath)
+	gav, err := readMavenGAV(primary)
+	if err == nil {
+		return gav.coordinates(filepath.Base(primary), suffix), nil
+	}
+	if coords, ok := parseMavenPath(artifact.Path); ok {
+		return coords, nil
+	}
+	return mavenCoordinates{}, fmt.Errorf("cannot resolve Maven coordinates of %s: %v, and the path is not in group/artifact/version layout", artifact.Path, err)
+}
+
+// readMavenGAV читает координаты файла primary: из него самого, если это POM,
+// из POM рядом с ним или из pom.properties внутри архива.
+func readMavenGAV(primary string) (mavenGAV, error) {
+	ext := strings.ToLower(filepath.Ext(primary))
+	if ext == ".pom" {
+		return readPomFile(primary)
+	}
+	if gav, ok, err := findSiblingPom(primary); ok || err != nil {
+		return gav, err
+	}
+	if slices.Contains(mavenArchiveExtensions, ext) {
+		return readPomProperties(primary)
+	}
+	return mavenGAV{}, fmt.Errorf("no .pom found next to it")
+}
+
+// findSiblingPom ищет в каталоге файла POM, к которому файл относится: сначала
+// с тем же базовым именем, затем любой POM, чьи artifactId-version начинают имя файла.
+func findSiblingPom(primary string) (mavenGAV, bool, error) {
+	dir, name := filepath.Split(primary)
+	same := strings.TrimSuffix(primary, filepath.Ext(primary)) + ".pom"
+	if _, err := os.Stat(same); err == nil {
+		gav, err := readPomFile(same)
+		return gav, true, err
+	}
+
+	poms, err := filepath.Glob(filepath.Join(dir, "*.pom"))
+	if err != nil {
+		return mavenGAV{}, false, err
+	}
+	for _, pom := range poms {
+		gav, err := readPomFile(pom)
+		if err != nil {
+			continue
+		}
+		if _, _, ok := splitMavenFileName(name, gav.ArtifactID, gav.Version); ok {
+			return gav, true, nil
+		}
+	}
+	return mavenGAV{}, false, nil
+}
+
+// pomProject — поля POM, из которых берутся координаты.
+type pomProject struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+}
+
+// readPomFile читает координаты из POM. groupId и version наследуются от parent.
+func readPomFile(pomPath string) (mavenGAV, error) {
+	data, err := os.ReadFile(pomPath)
+	if err != nil {
+		return mavenGAV{}, fmt.Errorf("failed to read %s: %w", filepath.Base(pomPath), err)
+	}
+	var project pomProject
+	if err := xml.Unmarshal(data, &project); err != nil {
+		return mavenGAV{}, fmt.Errorf("failed to parse %s: %w", filepath.Base(pomPath), err)
+	}
+	gav := mavenGAV{GroupID: project.GroupID, ArtifactID: project.ArtifactID, Version: project.Version}
+	if gav.GroupID == "" {
+		gav.GroupID = project.Parent.GroupID
+	}
+	if gav.Version == "" {
+		gav.Version = project.Parent.Version
+	}
+	return gav, gav.validate(filepath.Base(pomPath))
+}
+
+// readPomProperties читает координаты из META-INF/maven/<groupId>/<artifactId>/pom.properties.
+// В fat jar таких файлов несколько; тогда выбирается тот, чьи artifactId-version начинают имя файла.
+func readPomProperties(archivePath string) (mavenGAV, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return mavenGAV{}, fmt.Errorf("no .pom found next to it and failed to open it as an archive: %w", err)
+	}
+	defer reader.Close()
+
+	var found []mavenGAV
+	for _, file := range reader.File {
+		if !strings.HasPrefix(file.Name, "META-INF/maven/") || path.Base(file.Name) != "pom.properties" {
+			continue
+		}
+		gav, err := readPropertiesEntry(file)
+		if err != nil {
+			return mavenGAV{}, err
+		}
+		found = append(found, gav)
+	}
+
+	switch len(found) {
+	case 0:
+		return mavenGAV{}, fmt.Errorf("no .pom found next to it and no META-INF/maven/**/pom.properties inside")
+	case 1:
+		return found[0], found[0].validate("pom.properties")
+	}
+	name := filepath.Base(archivePath)
+	for _, gav := range found {
+		if _, _, ok := splitMavenFileName(name, gav.ArtifactID, gav.Version); ok {
+			return gav, gav.validate("pom.properties")
+		}
+	}
+	return mavenGAV{}, fmt.Errorf("no .pom found next to it and %d pom.properties inside, none matching the file name", len(found))
+}
+
+// readPropertiesEntry читает groupId, artifactId и version из pom.properties в архиве.
+func readPropertiesEntry(file *zip.File) (mavenGAV, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return mavenGAV{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	var gav mavenGAV
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "groupId":
+			gav.GroupID = value
+		case "artifactId":
+			gav.ArtifactID = value
+		case "version":
+			gav.Version = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return mavenGAV{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return gav, nil
+}
+
+// validate проверяет, что координаты заданы полностью и не ссылаются на свойства,
+// которые раскрывает только Maven (например, ${revision}).
+func (g mavenGAV) validate(source string) error {
+	for _, field := range []struct{ name, value string }{
+		{"groupId", g.GroupID}, {"artifactId", g.ArtifactID}, {"version", g.Version},
+	} {
+		if field.value == "" {
+			return fmt.Errorf("%s has no %s", source, field.name)
+		}
+		if strings.Contains(field.value, "${") {
+			return fmt.Errorf("%s %s %q uses a property", source, field.name, field.value)
+		}
+	}
+	return nil
+}
+
+// uploadMavenChecksums загружает контрольные суммы локального файла, которых нет рядом с ним на диске.
+// Файлы с контрольными суммами, которые есть на диске, загружаются отдельно как обычные файлы импорта.
+func uploadMavenChecksums(ctx context.Context, apiURL string, artifact Artifact, auth Auth) error {
+	if artifact.LocalPath == "" || isMavenChecksum(artifact.Path) {
+		return nil
+	}
+
+	hashes := make(map[string]hash.Hash)
+	var writers []io.Writer
+	for _, algo := range checksumAlgorithms {
+		if !slices.Contains(mavenGeneratedChecksums, algo.Name) {
+			continue
+		}
+		if _, err := os.Stat(artifact.LocalPath + "." + algo.Name); err == nil {
+			continue
+		}
+		hashes[algo.Name] = algo.New()
+		writers = append(writers, hashes[algo.Name])
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	file, err := os.Open(artifact.LocalPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", artifact.LocalPath, err)
+	}
+
+	for _, name := range mavenGeneratedChecksums {
+		h, ok := hashes[name]
+		if !ok {
+			continue
+		}
+		sum := []byte(hex.EncodeToString(h.Sum(nil)))
+		if err := putMavenFile(ctx, apiURL+"."+name, bytesBody(sum), auth); err != nil {
+			return fmt.Errorf("failed to upload %s checksum: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// putMavenFile загружает файл в hosted-репозиторий Maven запросом PUT.
+func putMavenFile(ctx context.Context, apiURL string, body bodySource, auth Auth) error {
+	resp, err := executeNexusRequest(ctx, "PUT", apiURL, "application/octet-stream", body, auth)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return statusErrorBody("failed to upload file", resp)
+	}
+	return nil
+}
+
+// bytesBody возвращает bodySource с содержимым data.
+func bytesBody(data []byte) bodySource {
+	return func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+	}
+}
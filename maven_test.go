@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseMavenPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		coords mavenCoordinates
+		ok     bool
+	}{
+		{"com/example/app/1.0/app-1.0.jar", mavenCoordinates{"com.example", "app", "1.0", "", "jar", ""}, true},
+		{"com/example/app/1.0/app-1.0-sources.jar", mavenCoordinates{"com.example", "app", "1.0", "sources", "jar", ""}, true},
+		{"com/example/app/1.0/app-1.0.pom.sha1", mavenCoordinates{"com.example", "app", "1.0", "", "pom.sha1", ""}, true},
+		{"com/example/app/1.0/app-1.0-javadoc.jar.asc", mavenCoordinates{"com.example", "app", "1.0", "javadoc", "jar.asc", ""}, true},
+		{"com/example/app/1.0/app-1.0.module", mavenCoordinates{"com.example", "app", "1.0", "", "module", ""}, true},
+		{"com/example/app/1.0-SNAPSHOT/app-1.0-20240115.103000-3-tests.jar", mavenCoordinates{"com.example", "app", "1.0-SNAPSHOT", "tests", "jar", ""}, true},
+		{"com/example/app/1.0/other-1.0.jar", mavenCoordinates{}, false},
+		{"com/example/app/1.0/app-2.0.jar", mavenCoordinates{}, false},
+		{"app/1.0/app-1.0.jar", mavenCoordinates{}, false},
+		{"com/example/app/1.0/app-1.0.tar.gz.sha1", mavenCoordinates{"com.example", "app", "1.0", "", "tar.gz.sha1", ""}, true},
+		{"com/example/app/1.0/app-1.0.jar.lastUpdated", mavenCoordinates{}, false},
+		{"com/example/app/1.0/app-1.0.pom.lastUpdated", mavenCoordinates{}, false},
+	}
+	for _, tt := range tests {
+		coords, ok := parseMavenPath(tt.path)
+		coords.FileName = ""
+		if ok != tt.ok || coords != tt.coords {
+			t.Errorf("parseMavenPath(%q) = %+v, %v; want %+v, %v", tt.path, coords, ok, tt.coords, tt.ok)
+		}
+	}
+
+	// maven-metadata.xml загружается обычным PUT, но не через components API
+	uploader, components := &MavenUploader{}, &MavenUploader{Components: true}
+	for _, name := range []string{"com/example/app/maven-metadata.xml", "com/example/app/1.0/app-1.0.jar.md5"} {
+		if !uploader.IsSupported(name) || components.IsSupported(name) {
+			t.Errorf("Unexpected IsSupported for %s", name)
+		}
+	}
+
+	// Служебные файлы копии ~/.m2 не загружаются
+	for _, name := range []string{
+		"com/example/app/1.0/app-1.0.jar.lastUpdated",
+		"com/example/app/1.0/app-1.0.pom.lastUpdated",
+		"com/example/app/1.0/_remote.repositories",
+		"com/example/app/resolver-status.properties",
+		"com/example/app/maven-metadata-central.xml",
+		"com/example/app/maven-metadata-central.xml.sha1",
+		"lib/gson.jar.lastUpdated",
+	} {
+		if uploader.IsSupported(name) {
+			t.Errorf("Expected %s not to be supported", name)
+		}
+	}
+}
+
+func TestImportMavenChecksums(t *testing.T) {
+	// 1. У jar на диске уже есть .sha1, у pom контрольных сумм нет
+	var mu sync.Mutex
+	var puts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		puts = append(puts, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	versionDir := filepath.Join(dir, "com", "example", "app", "1.0")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"app-1.0.jar":      "jar",
+		"app-1.0.jar.sha1": "5cc0c4c1fbc6a1a4b24ba1efec5e5fe4ea7f8ab3",
+		"app-1.0.pom":      "<project/>",
+		"notes.txt":        "not a maven file",
+	} {
+		if err := os.WriteFile(filepath.Join(versionDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 2. Загружаются все файлы раскладки, а недостающие суммы генерируются
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "maven-releases",
+		RepoType:  "maven",
+		ImportDir: dir,
+		Workers:   2,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	sort.Strings(puts)
+	prefix := "/repository/maven-releases/com/example/app/1.0/"
+	want := []string{"app-1.0.jar", "app-1.0.jar.md5", "app-1.0.jar.sha1", "app-1.0.pom", "app-1.0.pom.md5", "app-1.0.pom.sha1"}
+	if len(puts) != len(want) {
+		t.Fatalf("Expected uploads %v, got %v", want, puts)
+	}
+	for i, name := range want {
+		if puts[i] != prefix+name {
+			t.Errorf("Expected %s, got %s", prefix+name, puts[i])
+		}
+	}
+}
+
+func TestUploadFileMavenComponent(t *testing.T) {
+	// 1. Сервер проверяет координаты в форме components API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/service/rest/v1/components" || r.URL.Query().Get("repository") != "maven-releases" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		expected := map[string]string{
+			"maven2.groupId":           "com.example",
+			"maven2.artifactId":        "app",
+			"maven2.version":           "1.0",
+			"maven2.asset1.classifier": "sources",
+			"maven2.asset1.extension":  "jar",
+		}
+		for name, value := range expected {
+			if got := r.FormValue(name); got != value {
+				t.Errorf("Expected %s=%q, got %q", name, value, got)
+			}
+		}
+		if _, header, err := r.FormFile("maven2.asset1"); err != nil || header.Filename != "app-1.0-sources.jar" {
+			t.Errorf("Expected file part app-1.0-sources.jar, got %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// 2. Загружаем jar с классификатором
+	dir := t.TempDir()
+	versionDir := filepath.Join(dir, "com", "example", "app", "1.0")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(versionDir, "app-1.0-sources.jar")
+	if err := os.WriteFile(filePath, []byte("sources"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uploader := &MavenUploader{Components: true}
+	if err := uploader.Upload(context.Background(), server.URL, "maven-releases", mustLocalArtifact(t, filePath, dir), nil, false); err != nil {
+		t.Errorf("Upload failed: %v", err)
+	}
+}
+
+// writeJar создает jar с файлами files внутри.
+func writeJar(t *testing.T, jarPath string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(jarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	writer := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportMavenFlatDirectory(t *testing.T) {
+	// 1. Плоская lib/: координаты берутся из POM рядом с jar или из pom.properties внутри jar
+	var mu sync.Mutex
+	var puts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMavenChecksum(r.URL.Path) {
+			mu.Lock()
+			puts = append(puts, r.URL.Path)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	if err := os.MkdirAll(lib, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pom := `<project><parent><groupId>com.example</groupId><version>2.1</version></parent><artifactId>core</artifactId></project>`
+	if err := os.WriteFile(filepath.Join(lib, "core-2.1.pom"), []byte(pom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeJar(t, filepath.Join(lib, "core-2.1.jar"), nil)
+	writeJar(t, filepath.Join(lib, "core-2.1-sources.jar"), nil)
+	writeJar(t, filepath.Join(lib, "gson.jar"), map[string]string{
+		"META-INF/maven/com.google.code.gson/gson/pom.properties": "#Generated by Maven\ngroupId=com.google.code.gson\nartifactId=gson\nversion=2.10.1\n",
+	})
+	writeJar(t, filepath.Join(lib, "unknown.jar"), nil)
+
+	// 2. Файлы попадают по своему GAV, а файл без координат — в ошибки с объяснением
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "maven-releases",
+		RepoType:  "maven",
+		ImportDir: dir,
+		Workers:   2,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+	var partial *PartialFailure
+	if !errors.As(err, &partial) || len(partial.Failed) != 1 {
+		t.Fatalf("Expected one failed file, got %v", err)
+	}
+	if reason := partial.Failed[0].Err.Error(); !strings.Contains(reason, "cannot resolve Maven coordinates of lib/unknown.jar") {
+		t.Errorf("Unexpected diagnostics: %s", reason)
+	}
+
+	sort.Strings(puts)
+	want := []string{
+		"/repository/maven-releases/com/example/core/2.1/core-2.1-sources.jar",
+		"/repository/maven-releases/com/example/core/2.1/core-2.1.jar",
+		"/repository/maven-releases/com/example/core/2.1/core-2.1.pom",
+		"/repository/maven-releases/com/google/code/gson/gson/2.10.1/gson-2.10.1.jar",
+	}
+	if strings.Join(puts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected uploads %v, got %v", want, puts)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// MigrateOptions задает параметры переноса репозитория между экземплярами Nexus.
+type MigrateOptions struct {
+	SourceURL  string
+	SourceRepo string
+	SourceAuth Auth
+
+	TargetURL  string
+	TargetRepo string
+	TargetAuth Auth
+
+	RepoType  string
+	DryRun    bool
+	Workers   int
+	QueueSize int
+
+	// NugetAPIKey — ключ для заголовка X-NuGet-ApiKey при публикации пакетов NuGet.
+	NugetAPIKey string
+
+	Journal string // путь к журналу; пусто — путь по умолчанию
+	Resume  bool   // продолжить прерванный запуск по журналу
+
+	Report *Report // отчет по файлам; nil — не вести
+}
+
+// MigrateRepository переносит ассеты из одного репозитория в другой без промежуточной
+// записи на диск: каждый ассет скачивается потоком и сразу передается загрузчику
+// для типа репозитория. Листинг, очередь, журнал и отмена общие с ExportFiles.
+func MigrateRepository(ctx context.Context, opts MigrateOptions) error {
+	uploader, ok := GetUploader(opts.RepoType)
+	if !ok {
+		return fmt.Errorf("неподдерживаемый тип репозитория: %s", opts.RepoType)
+	}
+	if opts.NugetAPIKey != "" && opts.RepoType == "nuget" {
+		uploader = &NugetUploader{APIKey: opts.NugetAPIKey}
+	}
+
+	// Дополнения к пакетам (пакеты символов NuGet) переносятся вторым проходом,
+	// когда известно, перенесен ли их основной пакет.
+	companions, _ := uploader.(companionUploader)
+	var mu sync.Mutex
+	mainMigrated := map[string]bool{} // основные пакеты этого запуска: перенесен ли
+	process := func(ctx context.Context, task downloadTask) (string, error) {
+		if companions == nil {
+			return migrateAsset(ctx, uploader, task, opts)
+		}
+		if companions.IsCompanion(task.FilePath) {
+			mainPath := companions.MainPath(task.FilePath)
+			mu.Lock()
+			migrated, listed := mainMigrated[mainPath]
+			mu.Unlock()
+			// Основной пакет, которого нет в этом запуске, уже перенесен ранее или живет в целевом репозитории.
+			if listed && !migrated {
+				return "migrated", fmt.Errorf("%s: main package %s was not migrated", task.FilePath, mainPath)
+			}
+			return migrateAsset(ctx, uploader, task, opts)
+		}
+		outcome, err := migrateAsset(ctx, uploader, task, opts)
+		mu.Lock()
+		mainMigrated[task.FilePath] = err == nil && outcome == "migrated"
+		mu.Unlock()
+		return outcome, err
+	}
+	var deferCompanion func(task downloadTask) bool
+	if companions != nil {
+		deferCompanion = func(task downloadTask) bool { return companions.IsCompanion(task.FilePath) }
+	}
+
+	exporter := GetExporter(opts.RepoType)
+	run, err := runAssetPipeline(ctx, assetPipeline{
+		Run: journalRun{
+			Action:   "migrate",
+			RepoURL:  opts.TargetURL,
+			RepoName: opts.TargetRepo,
+			Source:   opts.SourceURL + "/repository/" + opts.SourceRepo,
+		},
+		Journal:   opts.Journal,
+		Resume:    opts.Resume,
+		DryRun:    opts.DryRun,
+		Workers:   opts.Workers,
+		QueueSize: opts.QueueSize,
+		Report:    opts.Report,
+		Feed: assetFeed{
+			RepoURL:  opts.SourceURL,
+			RepoName: opts.SourceRepo,
+			Auth:     opts.SourceAuth,
+			NewTask: func(asset Asset) downloadTask {
+				// Путь в раскладке экспорта: загрузчики разбирают его так же, как путь в директории импорта.
+				return assetTask(asset, exporter.GetLocalPath(asset.Path))
+			},
+		},
+		Process:     process,
+		Defer:       deferCompanion,
+		Title:       "Migrating",
+		Noun:        "Перенос",
+		Done:        "перенесено",
+		Failure:     "Ошибка переноса",
+		Interrupted: "migration interrupted",
+	})
+	if err != nil || run.Empty() {
+		return err
+	}
+
+	total, failedCount, skippedCount := run.Total, len(run.Failed), run.Outcomes["unsupported"]
+	migrated := total - failedCount - skippedCount
+	if opts.DryRun {
+		fmt.Printf("[Dry Run] Было бы перенесено %d ассетов, пропущено неподдерживаемых: %d.\n", migrated, skippedCount)
+	} else {
+		fmt.Printf("Всего обработано ассетов: %d, перенесено: %d, пропущено неподдерживаемых: %d, с ошибками: %d\n",
+			total, migrated, skippedCount, failedCount)
+	}
+
+	return partialFailure("ассетов не удалось перенести", total, run.Failed)
+}
+
+// migrateAsset передает один ассет из исходного репозитория загрузчику целевого
+// и возвращает итог: "migrated" или "unsupported", если формат не поддерживается загрузчиком.
+func migrateAsset(ctx context.Context, uploader Uploader, task downloadTask, opts MigrateOptions) (string, error) {
+	if !uploader.IsSupported(task.FilePath) {
+		return "unsupported", nil
+	}
+
+	artifact := remoteArtifact(task, opts.SourceAuth)
+	err := uploader.Upload(ctx, opts.TargetURL, opts.TargetRepo, artifact, opts.TargetAuth, opts.DryRun)
+	var skip *notPackageError
+	if errors.As(err, &skip) {
+		fmt.Fprintf(os.Stderr, "Пропущен %s: %s\n", task.FilePath, skip.Reason)
+		return "unsupported", nil
+	}
+	if err != nil {
+		return "migrated", fmt.Errorf("%s: %w", task.FilePath, err)
+	}
+	return "migrated", nil
+}
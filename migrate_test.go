@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMigrateRepository(t *testing.T) {
+	// 1. Исходный Nexus: два ассета, у b.txt неверная контрольная сумма
+	var source *httptest.Server
+	source = httptest.NewServer(requireBasicAuth("src", "src-secret", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{
+				Items: []Asset{
+					{
+						DownloadURL: source.URL + "/repository/src-raw/dir/a.txt",
+						Path:        "dir/a.txt",
+						FileSize:    11,
+						Checksum:    map[string]string{"sha1": "2aae6c35c94fcfb415dbe95f408b9ce91ee846ed"},
+					},
+					{
+						DownloadURL: source.URL + "/repository/src-raw/b.txt",
+						Path:        "b.txt",
+						Checksum:    map[string]string{"sha1": "0000000000000000000000000000000000000000"},
+					},
+				},
+			})
+		default:
+			w.Write([]byte("hello world"))
+		}
+	}))
+	defer source.Close()
+
+	// 2. Целевой Nexus принимает PUT со своими учетными данными
+	var mu sync.Mutex
+	received := map[string]string{}
+	target := httptest.NewServer(requireBasicAuth("dst", "dst-secret", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			// Тело оборвано клиентом из-за несовпадения контрольной суммы.
+			return
+		}
+		mu.Lock()
+		received[r.URL.Path] = string(body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer target.Close()
+
+	dir := chdirTemp(t)
+	err := MigrateRepository(context.Background(), MigrateOptions{
+		SourceURL:  source.URL,
+		SourceRepo: "src-raw",
+		SourceAuth: BasicAuth{Username: "src", Password: "src-secret"},
+		TargetURL:  target.URL,
+		TargetRepo: "dst-raw",
+		TargetAuth: BasicAuth{Username: "dst", Password: "dst-secret"},
+		RepoType:   "raw",
+		Workers:    2,
+		QueueSize:  10,
+	})
+
+	// 3. a.txt перенесен, b.txt отклонен из-за контрольной суммы
+	if err == nil {
+		t.Error("Expected migration to report the corrupted asset")
+	}
+	if got := received["/repository/dst-raw/dir/a.txt"]; got != "hello world" {
+		t.Errorf("Expected a.txt to be migrated, got %q", got)
+	}
+	if _, ok := received["/repository/dst-raw/b.txt"]; ok {
+		t.Error("Expected corrupted b.txt not to be stored in the target repository")
+	}
+
+	// 4. На диске ничего, кроме журнала упавшего запуска
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read working directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != defaultJournalPath("migrate", "dst-raw") {
+			t.Errorf("Unexpected file on disk after migration: %s", entry.Name())
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// npmManifestPath — путь package.json внутри тарбола, который публикует npm pack.
+const npmManifestPath = "package/package.json"
+
+// npmPackumentFile — имя файла, в котором экспорт сохраняет packument пакета рядом с его тарболами.
+// В packument хранятся dist-tags, пометки deprecated и время публикации версий.
+const npmPackumentFile = "packument.json"
+
+// npmPackageName — допустимое имя пакета, в том числе scoped (@org/pkg).
+// Заглавные буквы разрешены: они встречаются у старых пакетов в реестре.
+var npmPackageName = regexp.MustCompile(`^(@[A-Za-z0-9~-][A-Za-z0-9._~-]*/)?[A-Za-z0-9~-][A-Za-z0-9._~-]*$`)
+
+// npmVersion — версия semver, которую принимает реестр npm.
+var npmVersion = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// readNpmPackage читает имя и версию из package/package.json внутри тарбола.
+// Файлы, которые не являются npm-пакетами, например Helm-чарты, возвращают *notPackageError.
+func readNpmPackage(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	body, err := artifact.Open(ctx)
+	if err != nil {
+		return packageInfo{}, err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return packageInfo{}, notPackage("not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return packageInfo{}, notPackage("no %s in the tarball", npmManifestPath)
+		}
+		if err != nil {
+			return packageInfo{}, notPackage("broken tar archive: %v", err)
+		}
+		if header.Name != npmManifestPath && header.Name != "./"+npmManifestPath {
+			continue
+		}
+
+		var manifest struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return packageInfo{}, notPackage("%s is not valid JSON: %v", npmManifestPath, err)
+		}
+		if len(manifest.Name) > 214 || !npmPackageName.MatchString(manifest.Name) {
+			return packageInfo{}, notPackage("invalid package name %q in %s", manifest.Name, npmManifestPath)
+		}
+		if !npmVersion.MatchString(manifest.Version) {
+			return packageInfo{}, notPackage("invalid version %q of %s in %s", manifest.Version, manifest.Name, npmManifestPath)
+		}
+		return packageInfo{Name: manifest.Name, Version: manifest.Version}, nil
+	}
+}
+
+// npmEscapeName кодирует имя пакета для URL реестра: @org/pkg -> @org%2fpkg.
+func npmEscapeName(name string) string {
+	return strings.Replace(name, "/", "%2f", 1)
+}
+
+// npmExportedPackages возвращает имена пакетов, тарболы которых лежат в дереве экспорта dir.
+// После замены "/-/" тарбол лежит в каталоге с именем пакета: pkg/ или @org/pkg/.
+func npmExportedPackages(dir string) ([]string, error) {
+	seen := map[string]bool{}
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(filePath, ".tgz") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); npmPackageName.MatchString(name) {
+			seen[name] = true
+		}
+		return nil
+	})
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, err
+}
+
+// IsMetadata сообщает, что файл — сохраненный экспортом packument.
+func (e *NpmExporter) IsMetadata(relPath string) bool {
+	return path.Base(relPath) == npmPackumentFile
+}
+
+// ExportMetadata скачивает packument каждого экспортированного пакета
+// с /repository/<repo>/<name> и сохраняет его как <name>/packument.json.
+func (e *NpmExporter) ExportMetadata(ctx context.Context, opts ExportOptions, exportDir string, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error) {
+	names, err := npmExportedPackages(exportDir)
+	if err != nil {
+		return 0, []FailedItem{{Path: exportDir, Err: fmt.Errorf("failed to list exported packages: %w", err)}}, nil
+	}
+
+	var failed []FailedItem
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		destination := filepath.Join(exportDir, filepath.FromSlash(name), npmPackumentFile)
+		if snapshot.MetadataDone(destination) {
+			continue
+		}
+		journalWarn(journal.AddMetadata(destination))
+		taskCtx, stats := trackRequests(ctx)
+		started := time.Now()
+		packumentURL := fmt.Sprintf("%s/repository/%s/%s", opts.RepoURL, opts.RepoName, npmEscapeName(name))
+		err := downloadFile(taskCtx, packumentURL, destination, nil, opts.Auth, opts.DryRun)
+		if err != nil && ctx.Err() != nil {
+			break // скачивание прервано отменой, а не ошибкой
+		}
+		opts.Report.Add(stats.item(destination, packumentURL, exportNew.String(), started, err))
+		journalWarn(journal.FinishMetadata(destination, err))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка скачивания packument %s: %v\n", name, err)
+			failed = append(failed, FailedItem{Path: destination, Err: err})
+		}
+	}
+	if ctx.Err() != nil {
+		return len(names), failed, ctx.Err()
+	}
+	if !opts.DryRun {
+		fmt.Printf("Сохранено packument: %d из %d\n", len(names)-len(failed), len(names))
+	}
+	return len(names), failed, nil
+}
+
+// npmPackument — поля packument, которые нужны импорту.
+type npmPackument struct {
+	Name     string            `json:"name"`
+	DistTags map[string]string `json:"dist-tags"`
+}
+
+// ImportMetadata восстанавливает dist-tags из packument.json, сохраненных экспортом,
+// через npm dist-tag API: PUT /repository/<repo>/-/package/<name>/dist-tags/<tag>.
+// Вызывается после загрузки тарболов, чтобы версии, на которые указывают теги, уже были в репозитории.
+func (u *NpmUploader) ImportMetadata(ctx context.Context, opts ImportOptions, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error) {
+	var packuments []string
+	err := filepath.Walk(opts.ImportDir, func(filePath string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && info.Name() == npmPackumentFile {
+			packuments = append(packuments, filePath)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, []FailedItem{{Path: opts.ImportDir, Err: fmt.Errorf("failed to find packuments: %w", err)}}, nil
+	}
+
+	var failed []FailedItem
+	tags := 0
+	for _, packumentPath := range packuments {
+		if ctx.Err() != nil {
+			break
+		}
+		if snapshot.MetadataDone(packumentPath) {
+			continue
+		}
+		journalWarn(journal.AddMetadata(packumentPath))
+		taskCtx, stats := trackRequests(ctx)
+		started := time.Now()
+		n, err := restoreDistTags(taskCtx, opts, packumentPath)
+		if err != nil && ctx.Err() != nil {
+			break // восстановление прервано отменой, а не ошибкой
+		}
+		tags += n
+		opts.Report.Add(stats.item(packumentPath, "", "tagged", started, err))
+		journalWarn(journal.FinishMetadata(packumentPath, err))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка восстановления dist-tags: %v\n", err)
+			failed = append(failed, FailedItem{Path: packumentPath, Err: err})
+		}
+	}
+	if ctx.Err() != nil {
+		return len(packuments), failed, ctx.Err()
+	}
+	if opts.DryRun {
+		fmt.Printf("[Dry Run] Было бы восстановлено dist-tags: %d\n", tags)
+	} else if len(packuments) > 0 {
+		fmt.Printf("Восстановлено dist-tags: %d\n", tags)
+	}
+	return len(packuments), failed, nil
+}
+
+// restoreDistTags выставляет в репозитории dist-tags одного пакета и возвращает их число.
+func restoreDistTags(ctx context.Context, opts ImportOptions, packumentPath string) (int, error) {
+	data, err := os.ReadFile(packumentPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read packument: %w", err)
+	}
+	var packument npmPackument
+	if err := json.Unmarshal(data, &packument); err != nil {
+		return 0, fmt.Errorf("%s: invalid packument: %w", packumentPath, err)
+	}
+	if !npmPackageName.MatchString(packument.Name) {
+		return 0, fmt.Errorf("%s: invalid package name %q", packumentPath, packument.Name)
+	}
+
+	tagNames := make([]string, 0, len(packument.DistTags))
+	for tag := range packument.DistTags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+	for _, tag := range tagNames {
+		if opts.DryRun {
+			continue
+		}
+		version, _ := json.Marshal(packument.DistTags[tag])
+		apiURL := fmt.Sprintf("%s/repository/%s/-/package/%s/dist-tags/%s", opts.RepoURL, opts.RepoName, npmEscapeName(packument.Name), url.PathEscape(tag))
+		resp, err := executeNexusRequest(ctx, "PUT", apiURL, "application/json", bytesBody(version), opts.Auth)
+		if err != nil {
+			return 0, fmt.Errorf("failed to set dist-tag %s of %s: %w", tag, packument.Name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+			return 0, statusError(fmt.Sprintf("failed to set dist-tag %s of %s", tag, packument.Name), resp)
+		}
+	}
+	return len(tagNames), nil
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// writeTarball создает .tgz с файлами files.
+func writeTarball(t *testing.T, tgzPath string, files map[string]string) {
+	t.Helper()
+	out, err := os.Create(tgzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadNpmPackage(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		files  map[string]string
+		want   string
+		reason string
+	}{
+		{"scoped", map[string]string{"package/package.json": `{"name":"@org/pkg","version":"1.2.3-beta.1"}`}, "@org/pkg@1.2.3-beta.1", ""},
+		{"plain", map[string]string{"package/index.js": "", "package/package.json": `{"name":"left-pad","version":"1.3.0"}`}, "left-pad@1.3.0", ""},
+		{"chart", map[string]string{"mychart/Chart.yaml": "name: mychart"}, "", "no package/package.json"},
+		{"bad-version", map[string]string{"package/package.json": `{"name":"pkg","version":"latest"}`}, "", "invalid version"},
+		{"bad-name", map[string]string{"package/package.json": `{"name":"@org/","version":"1.0.0"}`}, "", "invalid package name"},
+	}
+	for _, tt := range tests {
+		tgz := filepath.Join(dir, tt.name+".tgz")
+		writeTarball(t, tgz, tt.files)
+		info, err := readNpmPackage(context.Background(), mustLocalArtifact(t, tgz, dir))
+		var skip *notPackageError
+		switch {
+		case tt.reason == "" && (err != nil || info.String() != tt.want):
+			t.Errorf("%s: expected %s, got %s (%v)", tt.name, tt.want, info, err)
+		case tt.reason != "" && (!errors.As(err, &skip) || !strings.Contains(skip.Reason, tt.reason)):
+			t.Errorf("%s: expected skip reason %q, got %v", tt.name, tt.reason, err)
+		}
+	}
+
+	// Файл, который даже не gzip, тоже пропускается с причиной
+	plain := filepath.Join(dir, "plain.tgz")
+	if err := os.WriteFile(plain, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var skip *notPackageError
+	if _, err := readNpmPackage(context.Background(), mustLocalArtifact(t, plain, dir)); !errors.As(err, &skip) {
+		t.Errorf("Expected non-gzip file to be skipped, got %v", err)
+	}
+}
+
+func TestImportNpmSkipsNonPackages(t *testing.T) {
+	// 1. В директории npm-пакет и Helm-чарт с тем же расширением
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeTarball(t, filepath.Join(dir, "org-pkg-1.0.0.tgz"), map[string]string{"package/package.json": `{"name":"@org/pkg","version":"1.0.0"}`})
+	writeTarball(t, filepath.Join(dir, "mychart-0.1.0.tgz"), map[string]string{"mychart/Chart.yaml": "name: mychart"})
+
+	// 2. Загружается только пакет; чарт пропущен с причиной, ошибки запуска нет
+	report := newReport("import", server.URL, "npm-internal", false)
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "npm-internal",
+		RepoType:  "npm",
+		ImportDir: dir,
+		Workers:   2,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+		Report:    report,
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if posts != 1 {
+		t.Errorf("Expected 1 upload, got %d", posts)
+	}
+
+	// 3. В отчете — name@version загруженного пакета и причина пропуска
+	for _, item := range report.Items {
+		switch filepath.Base(item.Path) {
+		case "org-pkg-1.0.0.tgz":
+			if item.Result != "uploaded" || item.Package != "@org/pkg@1.0.0" {
+				t.Errorf("Unexpected report item for the package: %+v", item)
+			}
+		case "mychart-0.1.0.tgz":
+			if item.Result != "unsupported" || !strings.Contains(item.Reason, "package/package.json") {
+				t.Errorf("Unexpected report item for the chart: %+v", item)
+			}
+		}
+	}
+}
+
+func TestExportNpmPackuments(t *testing.T) {
+	// 1. В репозитории тарбол scoped-пакета; packument отдается по имени пакета
+	packument := `{"name":"@org/pkg","dist-tags":{"latest":"1.0.0","next":"2.0.0-rc.1"}}`
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: server.URL + "/repository/npm-internal/@org/pkg/-/pkg-1.0.0.tgz", Path: "@org/pkg/-/pkg-1.0.0.tgz"},
+			}})
+		case "/repository/npm-internal/@org/pkg/-/pkg-1.0.0.tgz":
+			w.Write([]byte("tarball"))
+		case "/repository/npm-internal/@org/pkg":
+			if r.URL.RawPath != "/repository/npm-internal/@org%2fpkg" {
+				t.Errorf("Expected escaped scoped name, got %s", r.URL.RawPath)
+			}
+			w.Write([]byte(packument))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// 2. packument сохраняется рядом с тарболом
+	dir := chdirTemp(t)
+	err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "npm-internal", RepoType: "npm", Workers: 1, QueueSize: 10})
+	if err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "npm-internal", "@org", "pkg", npmPackumentFile))
+	if err != nil || string(data) != packument {
+		t.Fatalf("Expected packument next to the tarball, got %q (%v)", data, err)
+	}
+
+	// 3. diff не считает packument лишним файлом
+	items, err := listLocalItems(filepath.Join(dir, "npm-internal"), GetExporter("npm"))
+	if err != nil || len(items) != 1 {
+		t.Errorf("Expected only the tarball in the local tree, got %v (%v)", items, err)
+	}
+}
+
+func TestImportNpmDistTags(t *testing.T) {
+	// 1. Сервер принимает тарбол и dist-tags
+	var mu sync.Mutex
+	tags := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tag, ok := strings.CutPrefix(r.URL.Path, "/repository/npm-internal/-/package/@org/pkg/dist-tags/"); ok {
+			if r.Method != http.MethodPut {
+				t.Errorf("Expected PUT for dist-tag, got %s", r.Method)
+			}
+			body, _ := io.ReadAll(r.Body)
+			var version string
+			json.Unmarshal(body, &version)
+			mu.Lock()
+			tags[tag] = version
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	pkgDir := filepath.Join(dir, "@org", "pkg")
+	if err := os.MkdirAll(pkgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTarball(t, filepath.Join(pkgDir, "pkg-1.0.0.tgz"), map[string]string{"package/package.json": `{"name":"@org/pkg","version":"1.0.0"}`})
+	packument := `{"name":"@org/pkg","dist-tags":{"latest":"1.0.0","next":"2.0.0-rc.1"}}`
+	if err := os.WriteFile(filepath.Join(pkgDir, npmPackumentFile), []byte(packument), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. После загрузки тарбола dist-tags восстанавливаются из packument
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "npm-internal",
+		RepoType:  "npm",
+		ImportDir: dir,
+		Workers:   1,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if tags["latest"] != "1.0.0" || tags["next"] != "2.0.0-rc.1" || len(tags) != 2 {
+		t.Errorf("Unexpected dist-tags: %v", tags)
+	}
+
+	// 3. Повторный запуск с -resume, когда загружать уже нечего, все равно восстанавливает dist-tags
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	run := journalRun{Action: "import", RepoURL: server.URL, RepoName: "npm-internal", Source: dir}
+	j, _, err := openJournal(journal, run, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarball := filepath.Join(pkgDir, "pkg-1.0.0.tgz")
+	if err := j.AddTask(tarball, uploadTask{FilePath: tarball}); err != nil {
+		t.Fatal(err)
+	}
+	j.Listed()
+	j.Finish(tarball, nil)
+	j.Close(false)
+	tags = map[string]string{}
+	err = ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "npm-internal",
+		RepoType:  "npm",
+		ImportDir: dir,
+		Workers:   1,
+		Journal:   journal,
+		Resume:    true,
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles with -resume failed: %v", err)
+	}
+	if len(tags) != 2 {
+		t.Errorf("Expected dist-tags to be restored on resume, got %v", tags)
+	}
+}
+
+func TestNpmMetadataFailureKeepsJournal(t *testing.T) {
+	// 1. Репозиторий отдает и принимает тарбол, а packument и dist-tags падают, пока fail установлен
+	tgz := filepath.Join(t.TempDir(), "pkg-1.0.0.tgz")
+	writeTarball(t, tgz, map[string]string{"package/package.json": `{"name":"pkg","version":"1.0.0"}`})
+	tarball, err := os.ReadFile(tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fail atomic.Bool
+	fail.Store(true)
+	var downloads, uploads int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: server.URL + "/repository/npm-internal/pkg/-/pkg-1.0.0.tgz", Path: "pkg/-/pkg-1.0.0.tgz"},
+			}})
+		case r.URL.Path == "/repository/npm-internal/pkg/-/pkg-1.0.0.tgz":
+			atomic.AddInt32(&downloads, 1)
+			w.Write(tarball)
+		case r.URL.Path == "/service/rest/v1/components":
+			atomic.AddInt32(&uploads, 1)
+			w.WriteHeader(http.StatusNoContent)
+		case fail.Load():
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Path == "/repository/npm-internal/pkg":
+			w.Write([]byte(`{"name":"pkg","dist-tags":{"latest":"1.0.0"}}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	// 2. Экспорт: packument не скачан, журнал остается, -resume не скачивает тарбол повторно
+	dir := chdirTemp(t)
+	exportJournal := filepath.Join(t.TempDir(), "export.jsonl")
+	exportOpts := ExportOptions{RepoURL: server.URL, RepoName: "npm-internal", RepoType: "npm", Workers: 1, QueueSize: 10, Journal: exportJournal}
+	if err := ExportFiles(context.Background(), exportOpts); err == nil {
+		t.Fatal("Expected export to fail on the packument")
+	}
+	if _, err := os.Stat(exportJournal); err != nil {
+		t.Fatalf("Expected the export journal to be kept: %v", err)
+	}
+	fail.Store(false)
+	exportOpts.Resume = true
+	if err := ExportFiles(context.Background(), exportOpts); err != nil {
+		t.Fatalf("ExportFiles with -resume failed: %v", err)
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the tarball to be downloaded once, got %d", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "npm-internal", "pkg", npmPackumentFile)); err != nil {
+		t.Errorf("Expected the packument to be saved on resume: %v", err)
+	}
+	if _, err := os.Stat(exportJournal); !os.IsNotExist(err) {
+		t.Errorf("Expected the export journal to be removed, got %v", err)
+	}
+
+	// 3. Импорт: dist-tags не восстановлены, журнал остается, -resume не загружает тарбол повторно
+	fail.Store(true)
+	importJournal := filepath.Join(t.TempDir(), "import.jsonl")
+	importOpts := ImportOptions{RepoURL: server.URL, RepoName: "npm-internal", RepoType: "npm", ImportDir: filepath.Join(dir, "npm-internal"), Workers: 1, Journal: importJournal}
+	if err := ImportFiles(context.Background(), importOpts); err == nil {
+		t.Fatal("Expected import to fail on the dist-tags")
+	}
+	if _, err := os.Stat(importJournal); err != nil {
+		t.Fatalf("Expected the import journal to be kept: %v", err)
+	}
+	fail.Store(false)
+	importOpts.Resume = true
+	if err := ImportFiles(context.Background(), importOpts); err != nil {
+		t.Fatalf("ImportFiles with -resume failed: %v", err)
+	}
+	if uploads != 1 {
+		t.Errorf("Expected the tarball to be uploaded once, got %d", uploads)
+	}
+	if _, err := os.Stat(importJournal); !os.IsNotExist(err) {
+		t.Errorf("Expected the import journal to be removed, got %v", err)
+	}
+}
+
+func TestNpmMetadataInterrupted(t *testing.T) {
+	// 1. Отмена приходит во время запроса packument или dist-tags, после тарболов
+	tgz := filepath.Join(t.TempDir(), "pkg-1.0.0.tgz")
+	writeTarball(t, tgz, map[string]string{"package/package.json": `{"name":"pkg","version":"1.0.0"}`})
+	tarball, err := os.ReadFile(tgz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: server.URL + "/repository/npm-internal/pkg/-/pkg-1.0.0.tgz", Path: "pkg/-/pkg-1.0.0.tgz"},
+			}})
+		case "/repository/npm-internal/pkg/-/pkg-1.0.0.tgz":
+			w.Write(tarball)
+		case "/service/rest/v1/components":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			cancel()
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	// 2. Экспорт завершается прерыванием, а не успехом, и сохраняет журнал
+	dir := chdirTemp(t)
+	exportJournal := filepath.Join(t.TempDir(), "export.jsonl")
+	err = ExportFiles(ctx, ExportOptions{RepoURL: server.URL, RepoName: "npm-internal", RepoType: "npm", Workers: 1, QueueSize: 10, Journal: exportJournal})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an interrupted export, got %v", err)
+	}
+	if _, err := os.Stat(exportJournal); err != nil {
+		t.Errorf("Expected the export journal to be kept: %v", err)
+	}
+
+	// 3. Импорт тоже
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	importJournal := filepath.Join(t.TempDir(), "import.jsonl")
+	if err := os.WriteFile(filepath.Join(dir, "npm-internal", "pkg", npmPackumentFile), []byte(`{"name":"pkg","dist-tags":{"latest":"1.0.0"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = ImportFiles(ctx, ImportOptions{RepoURL: server.URL, RepoName: "npm-internal", RepoType: "npm", ImportDir: filepath.Join(dir, "npm-internal"), Workers: 1, Journal: importJournal})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an interrupted import, got %v", err)
+	}
+	if _, err := os.Stat(importJournal); err != nil {
+		t.Errorf("Expected the import journal to be kept: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nugetPackageID — допустимый идентификатор пакета NuGet.
+var nugetPackageID = regexp.MustCompile(`^\w+([_.-]\w+)*$`)
+
+// nugetVersion — версия NuGet: SemVer 2 или старая версия из четырех чисел.
+var nugetVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+){1,3}(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// nugetSymbolPublishType — префикс типа ресурса в индексе служб NuGet V3,
+// который принимает пакеты символов: SymbolPackagePublish/4.9.0.
+const nugetSymbolPublishType = "SymbolPackagePublish/"
+
+// isNugetSymbols сообщает, что файл — пакет символов .snupkg.
+func isNugetSymbols(filePath string) bool {
+	return strings.HasSuffix(filePath, ".snupkg")
+}
+
+// isNugetLegacySymbols сообщает, что файл — старый пакет символов .symbols.nupkg,
+// который публиковался на отдельный сервер символов, а не в репозиторий пакетов.
+func isNugetLegacySymbols(filePath string) bool {
+	return strings.HasSuffix(filePath, ".symbols.nupkg")
+}
+
+// readNugetPackage читает id и version из .nuspec в корне пакета.
+// Пакеты символов (.snupkg) содержат тот же .nuspec, что и основной пакет.
+// Файлы без .nuspec возвращают *notPackageError.
+func readNugetPackage(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	var nuspecName string
+	data, found, err := readZipEntry(ctx, artifact, func(entry string) bool {
+		if strings.Contains(entry, "/") || !strings.HasSuffix(strings.ToLower(entry), ".nuspec") {
+			return false
+		}
+		nuspecName = entry
+		return true
+	})
+	if err != nil {
+		return packageInfo{}, err
+	}
+	if !found {
+		return packageInfo{}, notPackage("no .nuspec in the package root")
+	}
+
+	// Пространство имен nuspec зависит от версии схемы, поэтому теги сопоставляются без него.
+	var nuspec struct {
+		Metadata struct {
+			ID      string `xml:"id"`
+			Version string `xml:"version"`
+		} `xml:"metadata"`
+	}
+	if err := xml.Unmarshal(data, &nuspec); err != nil {
+		return packageInfo{}, notPackage("%s is not valid XML: %v", nuspecName, err)
+	}
+	info := packageInfo{Name: strings.TrimSpace(nuspec.Metadata.ID), Version: strings.TrimSpace(nuspec.Metadata.Version)}
+	if len(info.Name) > 100 || !nugetPackageID.MatchString(info.Name) {
+		return packageInfo{}, notPackage("invalid package id %q in %s", info.Name, nuspecName)
+	}
+	if !nugetVersion.MatchString(info.Version) {
+		return packageInfo{}, notPackage("invalid version %q of %s in %s", info.Version, info.Name, nuspecName)
+	}
+	return info, nil
+}
+
+// nugetNormalizeVersion приводит версию к виду, в котором ее сравнивает NuGet:
+// 1.0 и 1.0.0.0 — это 1.0.0, метаданные сборки и регистр не учитываются.
+func nugetNormalizeVersion(version string) string {
+	version, _, _ = strings.Cut(strings.ToLower(version), "+")
+	release, pre, hasPre := strings.Cut(version, "-")
+	parts := strings.Split(release, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	if len(parts) == 4 && parts[3] == "0" {
+		parts = parts[:3]
+	}
+	for i, part := range parts {
+		if n, err := strconv.Atoi(part); err == nil {
+			parts[i] = strconv.Itoa(n)
+		}
+	}
+	version = strings.Join(parts, ".")
+	if hasPre {
+		version += "-" + pre
+	}
+	return version
+}
+
+// nugetAPIKeyAuth добавляет к запросу X-NuGet-ApiKey поверх обычных учетных данных,
+// как это делает nuget push.
+type nugetAPIKeyAuth struct {
+	Auth   Auth
+	APIKey string
+}
+
+func (a nugetAPIKeyAuth) Apply(req *http.Request) {
+	if a.Auth != nil {
+		a.Auth.Apply(req)
+	}
+	req.Header.Set("X-NuGet-ApiKey", a.APIKey)
+}
+
+// nugetSymbolEndpoint находит в индексе служб /repository/<repo>/index.json адрес,
+// по которому публикуются пакеты символов. Пустой адрес означает, что репозиторий
+// символы не принимает: индекса V3 нет или в нем нет ресурса SymbolPackagePublish.
+func nugetSymbolEndpoint(ctx context.Context, repoURL, repoName string, auth Auth) (string, error) {
+	apiURL := fmt.Sprintf("%s/repository/%s/index.json", repoURL, repoName)
+	resp, err := executeNexusRequest(ctx, "GET", apiURL, "", nil, auth)
+	if err != nil {
+		return "", fmt.Errorf("failed to read NuGet service index: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("failed to read NuGet service index, status", resp)
+	}
+
+	var index struct {
+		Resources []struct {
+			ID   string `json:"@id"`
+			Type string `json:"@type"`
+		} `json:"resources"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return "", fmt.Errorf("failed to decode NuGet service index: %w", err)
+	}
+	for _, resource := range index.Resources {
+		if strings.HasPrefix(resource.Type, nugetSymbolPublishType) && resource.ID != "" {
+			return resource.ID, nil
+		}
+	}
+	return "", nil
+}
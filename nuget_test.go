@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// nuspec возвращает .nuspec с id и version в пространстве имен схемы 2013 года.
+func nuspec(id, version string) string {
+	return `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata>
+    <id>` + id + `</id>
+    <version>` + version + `</version>
+    <authors>team</authors>
+  </metadata>
+</package>`
+}
+
+func TestReadNugetPackage(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file   string
+		files  map[string]string
+		want   string
+		reason string
+	}{
+		{"Contoso.Utils.1.2.0.nupkg", map[string]string{
+			"Contoso.Utils.nuspec":         nuspec("Contoso.Utils", "1.2.0"),
+			"lib/net8.0/Contoso.Utils.dll": "",
+			"content/other.nuspec":         nuspec("Other", "9.9.9"),
+		}, "Contoso.Utils@1.2.0", ""},
+		{"Contoso.Utils.1.2.0.snupkg", map[string]string{
+			"Contoso.Utils.nuspec":         nuspec("Contoso.Utils", "1.2.0"),
+			"lib/net8.0/Contoso.Utils.pdb": "",
+		}, "Contoso.Utils@1.2.0", ""},
+		{"archive.nupkg", map[string]string{"readme.txt": ""}, "", "no .nuspec"},
+		{"bad-id.nupkg", map[string]string{"bad.nuspec": nuspec("bad id", "1.0.0")}, "", "invalid package id"},
+		{"bad-version.nupkg", map[string]string{"bad.nuspec": nuspec("Bad", "latest")}, "", "invalid version"},
+	}
+	for _, tt := range tests {
+		archive := filepath.Join(dir, tt.file)
+		writeJar(t, archive, tt.files)
+		info, err := (&NugetUploader{}).Inspect(context.Background(), mustLocalArtifact(t, archive, dir))
+		var skip *notPackageError
+		switch {
+		case tt.reason == "" && (err != nil || info.String() != tt.want):
+			t.Errorf("%s: expected %s, got %s (%v)", tt.file, tt.want, info, err)
+		case tt.reason != "" && (!errors.As(err, &skip) || !strings.Contains(skip.Reason, tt.reason)):
+			t.Errorf("%s: expected skip reason %q, got %v", tt.file, tt.reason, err)
+		}
+	}
+	for version, want := range map[string]string{"1.0": "1.0.0", "1.0.0.0": "1.0.0", "01.2.3-RC.1+abc": "1.2.3-rc.1", "1.2.3.4": "1.2.3.4"} {
+		if got := nugetNormalizeVersion(version); got != want {
+			t.Errorf("nugetNormalizeVersion(%s) = %s, expected %s", version, got, want)
+		}
+	}
+}
+
+// nugetUpload — один PUT к тестовому репозиторию NuGet.
+type nugetUpload struct {
+	Path   string
+	APIKey string
+}
+
+// newNugetServer запускает репозиторий nuget-hosted. С symbols индекс служб объявляет
+// SymbolPackagePublish по адресу /repository/nuget-hosted/symbols/, без него индекса V3 нет.
+// С rejectMain основной пакет отклоняется со статусом 400.
+func newNugetServer(t *testing.T, symbols, rejectMain bool) (*httptest.Server, func() []nugetUpload) {
+	var mu sync.Mutex
+	var uploads []nugetUpload
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/repository/nuget-hosted/index.json" {
+			if !symbols {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"version":"3.0.0","resources":[
+				{"@id":"%[1]s/repository/nuget-hosted/","@type":"PackagePublish/2.0.0"},
+				{"@id":"%[1]s/repository/nuget-hosted/symbols/","@type":"SymbolPackagePublish/4.9.0"}]}`, server.URL)
+			return
+		}
+		if r.Method != http.MethodPut {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		mu.Lock()
+		uploads = append(uploads, nugetUpload{Path: r.URL.Path, APIKey: r.Header.Get("X-NuGet-ApiKey")})
+		mu.Unlock()
+		if rejectMain && r.URL.Path == "/repository/nuget-hosted/" {
+			http.Error(w, "package is invalid", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []nugetUpload {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]nugetUpload(nil), uploads...)
+	}
+}
+
+// writeNugetPackage пишет в dir пакет и пакет символов Contoso.Utils 1.0.0.
+func writeNugetPackage(t *testing.T, dir string) {
+	writeJar(t, filepath.Join(dir, "Contoso.Utils.1.0.0.nupkg"), map[string]string{"Contoso.Utils.nuspec": nuspec("Contoso.Utils", "1.0.0")})
+	writeJar(t, filepath.Join(dir, "Contoso.Utils.1.0.0.snupkg"), map[string]string{"Contoso.Utils.nuspec": nuspec("Contoso.Utils", "1.0.0")})
+}
+
+// importNuget импортирует dir в nuget-hosted и возвращает отчет по файлам.
+func importNuget(t *testing.T, serverURL, dir string) (map[string]ReportItem, error) {
+	report := newReport("import", serverURL, "nuget-hosted", false)
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:     serverURL,
+		RepoName:    "nuget-hosted",
+		RepoType:    "nuget",
+		ImportDir:   dir,
+		NugetAPIKey: "secret-key",
+		Workers:     2,
+		Journal:     filepath.Join(t.TempDir(), "journal.jsonl"),
+		Report:      report,
+	})
+	items := map[string]ReportItem{}
+	for _, item := range report.Items {
+		rel, _ := filepath.Rel(dir, item.Path)
+		items[filepath.ToSlash(rel)] = item
+	}
+	return items, err
+}
+
+func TestImportNugetDuplicatesAndAPIKey(t *testing.T) {
+	// 1. В директории пакет, его символы и копия того же пакета с другим именем файла и версией 1.0
+	server, uploads := newNugetServer(t, true, false)
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeNugetPackage(t, filepath.Join(dir, "a"))
+	writeJar(t, filepath.Join(dir, "b", "contoso.utils.1.0.nupkg"), map[string]string{"contoso.utils.nuspec": nuspec("contoso.utils", "1.0")})
+
+	// 2. Дубликат не загружается и считается ошибкой
+	items, err := importNuget(t, server.URL, dir)
+	if err == nil {
+		t.Fatal("Expected an error for the duplicate package")
+	}
+
+	// 3. Пакет ушел в корень репозитория, а символы после него — в службу SymbolPackagePublish, оба с ключом API
+	want := []nugetUpload{
+		{Path: "/repository/nuget-hosted/", APIKey: "secret-key"},
+		{Path: "/repository/nuget-hosted/symbols/", APIKey: "secret-key"},
+	}
+	if got := uploads(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected uploads %+v, got %+v", want, got)
+	}
+
+	// 4. В отчете ошибка дубликата ссылается на первый файл
+	for _, file := range []string{"a/Contoso.Utils.1.0.0.nupkg", "a/Contoso.Utils.1.0.0.snupkg"} {
+		if item := items[file]; item.Result != "uploaded" || item.Package != "Contoso.Utils@1.0.0" {
+			t.Errorf("Unexpected report item for %s: %+v", file, item)
+		}
+	}
+	if item := items["b/contoso.utils.1.0.nupkg"]; item.Result != reportResultFailed ||
+		!strings.Contains(item.Error, "duplicate package contoso.utils@1.0") || !strings.Contains(item.Error, "Contoso.Utils.1.0.0.nupkg") {
+		t.Errorf("Unexpected report item for the duplicate: %+v", item)
+	}
+}
+
+func TestImportNugetSymbols(t *testing.T) {
+	// 1. Основной пакет отклонен репозиторием: символы к нему не загружаются и считаются ошибкой
+	server, uploads := newNugetServer(t, true, true)
+	dir := t.TempDir()
+	writeNugetPackage(t, dir)
+	items, err := importNuget(t, server.URL, dir)
+	if err == nil {
+		t.Fatal("Expected an error for the rejected package")
+	}
+	if got := uploads(); len(got) != 1 || got[0].Path != "/repository/nuget-hosted/" {
+		t.Errorf("Expected only the main package upload, got %+v", got)
+	}
+	if item := items["Contoso.Utils.1.0.0.snupkg"]; item.Result != reportResultFailed ||
+		!strings.Contains(item.Error, "main package") || !strings.Contains(item.Error, "Contoso.Utils.1.0.0.nupkg") {
+		t.Errorf("Unexpected report item for the symbols: %+v", item)
+	}
+
+	// 2. Репозиторий не объявляет SymbolPackagePublish: символы пропускаются с причиной
+	server, uploads = newNugetServer(t, false, false)
+	items, err = importNuget(t, server.URL, dir)
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if got := uploads(); len(got) != 1 || got[0].Path != "/repository/nuget-hosted/" {
+		t.Errorf("Expected only the main package upload, got %+v", got)
+	}
+	if item := items["Contoso.Utils.1.0.0.snupkg"]; item.Result != "unsupported" || !strings.Contains(item.Reason, "does not accept symbol packages") {
+		t.Errorf("Unexpected report item for the symbols: %+v", item)
+	}
+
+	// 3. Старый .symbols.nupkg не считается дубликатом пакета и пропускается
+	writeJar(t, filepath.Join(dir, "Contoso.Utils.1.0.0.symbols.nupkg"), map[string]string{"Contoso.Utils.nuspec": nuspec("Contoso.Utils", "1.0.0")})
+	items, err = importNuget(t, server.URL, dir)
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if item := items["Contoso.Utils.1.0.0.symbols.nupkg"]; item.Result != "unsupported" || !strings.Contains(item.Reason, "use .snupkg") {
+		t.Errorf("Unexpected report item for the legacy symbols: %+v", item)
+	}
+}
+
+func TestMigrateNugetSymbolsAfterPackage(t *testing.T) {
+	// 1. Листинг источника отдает пакет символов раньше основного пакета
+	var source *httptest.Server
+	source = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: source.URL + "/repository/nuget-src/Contoso.Utils.1.0.0.snupkg", Path: "Contoso.Utils.1.0.0.snupkg"},
+				{DownloadURL: source.URL + "/repository/nuget-src/Contoso.Utils.1.0.0.nupkg", Path: "Contoso.Utils.1.0.0.nupkg"},
+			}})
+			return
+		}
+		w.Write([]byte("package"))
+	}))
+	defer source.Close()
+
+	for _, tt := range []struct {
+		name       string
+		rejectMain bool
+		want       []string
+	}{
+		// 2. Символы уходят в службу SymbolPackagePublish после пакета, оба с ключом API
+		{"accepted", false, []string{"/repository/nuget-hosted/", "/repository/nuget-hosted/symbols/"}},
+		// 3. Пакет отклонен: символы не переносятся
+		{"rejected", true, []string{"/repository/nuget-hosted/"}},
+	} {
+		target, uploads := newNugetServer(t, true, tt.rejectMain)
+		report := newReport("migrate", target.URL, "nuget-hosted", false)
+		err := MigrateRepository(context.Background(), MigrateOptions{
+			SourceURL:   source.URL,
+			SourceRepo:  "nuget-src",
+			TargetURL:   target.URL,
+			TargetRepo:  "nuget-hosted",
+			RepoType:    "nuget",
+			Workers:     2,
+			QueueSize:   10,
+			NugetAPIKey: "secret-key",
+			Journal:     filepath.Join(t.TempDir(), "journal.jsonl"),
+			Report:      report,
+		})
+		if tt.rejectMain != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		var paths []string
+		for _, upload := range uploads() {
+			paths = append(paths, upload.Path)
+			if upload.APIKey != "secret-key" {
+				t.Errorf("%s: expected X-NuGet-ApiKey on %s", tt.name, upload.Path)
+			}
+		}
+		if !reflect.DeepEqual(paths, tt.want) {
+			t.Errorf("%s: expected uploads %q, got %q", tt.name, tt.want, paths)
+		}
+		for _, item := range report.Items {
+			if item.Path != "Contoso.Utils.1.0.0.snupkg" {
+				continue
+			}
+			if tt.rejectMain != strings.Contains(item.Error, "main package Contoso.Utils.1.0.0.nupkg was not migrated") {
+				t.Errorf("%s: unexpected report item for the symbols: %+v", tt.name, item)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// packageInfo — имя и версия пакета, прочитанные из самого файла.
+type packageInfo struct {
+	Name    string
+	Version string
+}
+
+// String возвращает пакет в виде name@version, как его пишет отчет.
+func (p packageInfo) String() string {
+	return p.Name + "@" + p.Version
+}
+
+// packageInspector — загрузчик, который перед загрузкой читает метаданные пакета из файла.
+// Файл с подходящим расширением, который не оказался пакетом формата, пропускается
+// с причиной, а не отправляется в Nexus за невнятной ошибкой.
+type packageInspector interface {
+	Inspect(ctx context.Context, artifact Artifact) (packageInfo, error)
+}
+
+// duplicateDetector — загрузчик, для которого два файла с одним пакетом — ошибка:
+// репозиторий хранит одну копию версии, и второй файл заменил бы первый или был бы отклонен.
+type duplicateDetector interface {
+	packageInspector
+	// PackageKey возвращает ключ, по которому совпадают файлы одного пакета.
+	PackageKey(artifact Artifact, info packageInfo) string
+}
+
+// companionUploader — загрузчик, у которого часть файлов дополняет основной пакет
+// (пакеты символов NuGet). Такие файлы загружаются только после того, как основной
+// пакет загружен: import находит его по ключу PackageKey, migrate — по MainPath.
+type companionUploader interface {
+	IsCompanion(filePath string) bool
+	// MainPath возвращает путь основного пакета для файла-дополнения.
+	MainPath(filePath string) string
+}
+
+// packagePlan — то, что импорт узнал о пакетах до загрузки.
+type packagePlan struct {
+	Rejected map[string]error  // файлы, которые не загружаются: повторы пакетов
+	MainOf   map[string]string // файл-дополнение -> файл основного пакета из того же импорта
+}
+
+// planPackages читает пакеты из files до загрузки. Для каждого повторного файла
+// Rejected содержит ошибку со ссылкой на первый файл с тем же пакетом; основные
+// пакеты и дополнения к ним сравниваются отдельно. Файлы, которые не удалось
+// прочитать, здесь пропускаются: их ошибку сообщит сам импорт.
+func planPackages(ctx context.Context, detector duplicateDetector, files []string, importDir string) packagePlan {
+	type planKey struct {
+		Key       string
+		Companion bool
+	}
+	plan := packagePlan{Rejected: map[string]error{}, MainOf: map[string]string{}}
+	companions, _ := detector.(companionUploader)
+	first := map[planKey]string{}
+	companionKeys := map[string]string{}
+	for _, filePath := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		artifact, err := localArtifact(filePath, importDir)
+		if err != nil {
+			continue
+		}
+		info, err := detector.Inspect(ctx, artifact)
+		if err != nil {
+			continue
+		}
+		key := planKey{Key: detector.PackageKey(artifact, info)}
+		key.Companion = companions != nil && companions.IsCompanion(filePath)
+		if original, ok := first[key]; ok {
+			plan.Rejected[filePath] = fmt.Errorf("duplicate package %s: %s contains the same id and version", info, original)
+			continue
+		}
+		first[key] = filePath
+		if key.Companion {
+			companionKeys[filePath] = key.Key
+		}
+	}
+	// Основной пакет может оказаться в директории после своего дополнения.
+	for filePath, key := range companionKeys {
+		if main, ok := first[planKey{Key: key}]; ok {
+			plan.MainOf[filePath] = main
+		}
+	}
+	return plan
+}
+
+// notPackageError — файл не является пакетом формата репозитория. Reason объясняет почему.
+type notPackageError struct {
+	Reason string
+}
+
+func (e *notPackageError) Error() string {
+	return e.Reason
+}
+
+// notPackage возвращает *notPackageError с причиной, собранной как в fmt.Sprintf.
+func notPackage(format string, args ...any) error {
+	return &notPackageError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// readZipEntry читает из zip-архива первый файл, для которого match возвращает true.
+// found == false, если такого файла нет; архив, который не читается как zip, — *notPackageError.
+func readZipEntry(ctx context.Context, artifact Artifact, match func(entry string) bool) (data []byte, found bool, err error) {
+	var reader *zip.Reader
+	if artifact.LocalPath != "" {
+		file, err := zip.OpenReader(artifact.LocalPath)
+		if err != nil {
+			return nil, false, notPackage("not a zip archive: %v", err)
+		}
+		defer file.Close()
+		reader = &file.Reader
+	} else {
+		// zip читается с конца, поэтому удаленный артефакт приходится скачать в память.
+		body, err := artifact.Open(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		content, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", artifact.Path, err)
+		}
+		if reader, err = zip.NewReader(bytes.NewReader(content), int64(len(content))); err != nil {
+			return nil, false, notPackage("not a zip archive: %v", err)
+		}
+	}
+
+	for _, entry := range reader.File {
+		if !match(entry.Name) {
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, false, notPackage("broken zip archive: %v", err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, 16<<20))
+		if err != nil {
+			return nil, false, notPackage("broken zip archive: %v", err)
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
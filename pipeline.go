@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// assetPipeline — общая часть export и migrate: листинг репозитория по страницам,
+// очередь задач, пул воркеров, журнал, отчет и итог прерванного запуска.
+// Команды задают только обработку одного ассета и свою итоговую сводку.
+type assetPipeline struct {
+	Run       journalRun // запуск, которому принадлежит журнал
+	Journal   string     // путь к журналу; пусто — путь по умолчанию
+	Resume    bool
+	DryRun    bool
+	Workers   int
+	QueueSize int
+	Report    *Report
+
+	// Feed — источник ассетов. Journal, Snapshot и Grow заполняет конвейер.
+	Feed assetFeed
+
+	// Process обрабатывает одну задачу и возвращает итог для отчета: "new", "migrated" и т.п.
+	Process func(ctx context.Context, task downloadTask) (outcome string, err error)
+
+	// Defer, если задан, откладывает задачу до второго прохода: она выполняется после
+	// того, как листинг завершен и все остальные задачи обработаны.
+	Defer func(task downloadTask) bool
+
+	// Metadata, если задан, обрабатывает метаданные формата после ассетов, пока журнал
+	// еще открыт, и возвращает число документов и те из них, которые обработать не удалось.
+	// Ошибка означает, что шаг прерван отменой ctx.
+	Metadata func(ctx context.Context, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error)
+
+	Title       string // подпись прогресс-бара: "Exporting"
+	Noun        string // название операции в сообщениях: "Экспорт"
+	Done        string // что произошло с ассетом: "скачано"
+	Failure     string // префикс ошибки ассета: "Ошибка скачивания"
+	Interrupted string // текст ошибки при отмене: "export interrupted"
+}
+
+// pipelineRun — итог конвейера для сводки команды.
+type pipelineRun struct {
+	Total       int            // задач в этом запуске
+	ResumedDone int            // задач, выполненных еще в прерванном запуске
+	Metadata    int            // документов, обработанных шагом Metadata
+	Outcomes    map[string]int // число успешных задач по итогам Process
+	Failed      []FailedItem
+}
+
+// Empty сообщает, что в репозитории не нашлось ни одного ассета.
+func (r pipelineRun) Empty() bool {
+	return r.Total == 0 && r.ResumedDone == 0
+}
+
+// runAssetPipeline выполняет конвейер. Страницы листинга передаются воркерам по мере
+// получения. При отмене ctx новые задачи не начинаются, текущие прерываются,
+// и возвращается ошибка с подсказкой продолжить запуск с -resume.
+func runAssetPipeline(ctx context.Context, p assetPipeline) (pipelineRun, error) {
+	run := pipelineRun{Outcomes: map[string]int{}}
+	queueSize := p.QueueSize
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	// Без воркеров results закрылся бы сразу, еще до завершения листинга.
+	workers := p.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	var journal *Journal
+	var snapshot *journalSnapshot
+	if !p.DryRun {
+		journalPath := p.Journal
+		if journalPath == "" {
+			journalPath = defaultJournalPath(p.Run.Action, p.Run.RepoName)
+		}
+		var err error
+		journal, snapshot, err = openJournal(journalPath, p.Run, p.Resume)
+		if err != nil {
+			return run, err
+		}
+	}
+
+	// Общее число ассетов заранее неизвестно: максимум растет с каждой страницей.
+	bar := progressbar.NewOptions(0,
+		progressbar.OptionSetDescription(p.Title),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+
+	type taskResult struct {
+		Path    string
+		Outcome string
+		Err     error
+	}
+	tasks := make(chan downloadTask, queueSize)
+	results := make(chan taskResult, workers)
+
+	var deferredMu sync.Mutex
+	var deferred []downloadTask
+	// runWorkers обрабатывает задачи из queue и возвращается, когда очередь закрыта и пуста.
+	runWorkers := func(queue <-chan downloadTask, second bool) {
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 1; w <= workers; w++ {
+			go func() {
+				defer wg.Done()
+				for task := range queue {
+					if ctx.Err() != nil {
+						continue // после отмены оставшиеся задачи считаются невыполненными
+					}
+					if !second && p.Defer != nil && p.Defer(task) {
+						deferredMu.Lock()
+						deferred = append(deferred, task)
+						deferredMu.Unlock()
+						continue
+					}
+					taskCtx, stats := trackRequests(ctx)
+					started := time.Now()
+					outcome, err := p.Process(taskCtx, task)
+					if err != nil && ctx.Err() != nil {
+						continue // задача прервана отменой, а не ошибкой
+					}
+					p.Report.Add(stats.item(task.FilePath, task.URL, outcome, started, err))
+					journalWarn(journal.Finish(task.FilePath, err))
+					results <- taskResult{Path: task.FilePath, Outcome: outcome, Err: err}
+					bar.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	// --- Листинг: каждая страница сразу уходит в очередь ---
+	var fetchErr error
+	feed := p.Feed
+	feed.Journal, feed.Snapshot = journal, snapshot
+	feed.Grow = func(n int) {
+		run.Total += n
+		bar.ChangeMax(run.Total)
+	}
+	go func() {
+		defer close(tasks)
+		run.ResumedDone, fetchErr = feed.Run(ctx, tasks)
+	}()
+
+	go func() {
+		runWorkers(tasks, false)
+		// Отложенные задачи остаются в журнале в состоянии pending, пока не выполнены.
+		if len(deferred) > 0 && ctx.Err() == nil {
+			queue := make(chan downloadTask, len(deferred))
+			for _, task := range deferred {
+				queue <- task
+			}
+			close(queue)
+			runWorkers(queue, true)
+		}
+		close(results)
+	}()
+
+	processed := 0
+	for result := range results {
+		processed++
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", p.Failure, result.Err)
+			run.Failed = append(run.Failed, FailedItem{Path: result.Path, Err: result.Err})
+			continue
+		}
+		run.Outcomes[result.Outcome]++
+	}
+
+	// results закрывается только после завершения листинга, так что Total и fetchErr уже готовы.
+	failedCount := len(run.Failed)
+
+	// Журнал удаляется, только если успешны и ассеты, и метаданные: иначе -resume
+	// после ошибки в метаданных начал бы перенос заново.
+	var interrupted error
+	if p.Metadata != nil && ctx.Err() == nil && fetchErr == nil && !run.Empty() {
+		count, metadataFailed, err := p.Metadata(ctx, journal, snapshot)
+		run.Metadata, interrupted = count, err
+		run.Failed = append(run.Failed, metadataFailed...)
+	}
+	if interrupted == nil {
+		interrupted = ctx.Err()
+	}
+	journalWarn(journal.Close(interrupted == nil && fetchErr == nil && len(run.Failed) == 0))
+
+	if run.ResumedDone > 0 {
+		fmt.Printf("Продолжение прерванного запуска: %d ассетов уже было %s ранее.\n", run.ResumedDone, p.Done)
+	}
+	if interrupted != nil {
+		fmt.Printf("%s прерван: завершено %d, не выполнено %d из %d найденных ассетов, с ошибками: %d\n",
+			p.Noun, processed-failedCount, run.Total-processed, run.Total, failedCount)
+		fmt.Printf("Запустите %s повторно с -resume, чтобы продолжить.\n", strings.ToLower(p.Noun))
+		return run, fmt.Errorf("%s: %w", p.Interrupted, interrupted)
+	}
+	if fetchErr != nil {
+		return run, fmt.Errorf("error fetching assets: %w", fetchErr)
+	}
+	if run.Empty() {
+		fmt.Println("No assets found in the repository.")
+	}
+	return run, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pypiSimpleDir — каталог индекса PEP 503, который экспорт строит с -simple-index.
+const pypiSimpleDir = "simple"
+
+// pypiExtensions — форматы дистрибутивов Python: wheel, sdist (.tar.gz и .zip) и egg.
+var pypiExtensions = []string{".whl", ".tar.gz", ".zip", ".egg"}
+
+// pypiProjectName — имя проекта по PEP 508.
+var pypiProjectName = regexp.MustCompile(`^(?i)([a-z0-9]|[a-z0-9][a-z0-9._-]*[a-z0-9])$`)
+
+// pypiVersion — версия PEP 440 или одна из старых версий, которые еще встречаются в индексах.
+var pypiVersion = regexp.MustCompile(`^v?[0-9][0-9A-Za-z.!+_-]*$`)
+
+// pypiNameSeparators — разделители в имени проекта, которые PEP 503 сводит к одному дефису.
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// pypiNormalize приводит имя проекта к виду PEP 503: Foo.Bar_baz -> foo-bar-baz.
+func pypiNormalize(name string) string {
+	return strings.ToLower(pypiNameSeparators.ReplaceAllString(name, "-"))
+}
+
+// isPypiDistribution сообщает, что у файла расширение дистрибутива Python.
+func isPypiDistribution(filePath string) bool {
+	for _, ext := range pypiExtensions {
+		if strings.HasSuffix(filePath, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPypiDistribution читает Name и Version из метаданных дистрибутива:
+// <name>.dist-info/METADATA у wheel, EGG-INFO/PKG-INFO у egg, <dir>/PKG-INFO у sdist.
+// Архивы без метаданных Python возвращают *notPackageError.
+func readPypiDistribution(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	var metadata []byte
+	var err error
+	switch name := artifact.Name(); {
+	case strings.HasSuffix(name, ".tar.gz"):
+		metadata, err = readPypiTarMetadata(ctx, artifact)
+	case strings.HasSuffix(name, ".whl"):
+		metadata, err = readPypiZipMetadata(ctx, artifact, func(entry string) bool {
+			dir, file, ok := strings.Cut(entry, "/")
+			return ok && strings.HasSuffix(dir, ".dist-info") && file == "METADATA"
+		})
+	case strings.HasSuffix(name, ".egg"):
+		metadata, err = readPypiZipMetadata(ctx, artifact, func(entry string) bool {
+			return entry == "EGG-INFO/PKG-INFO"
+		})
+	case strings.HasSuffix(name, ".zip"):
+		metadata, err = readPypiZipMetadata(ctx, artifact, isSdistPkgInfo)
+	default:
+		return packageInfo{}, notPackage("not a Python distribution")
+	}
+	if err != nil {
+		return packageInfo{}, err
+	}
+	return parsePypiMetadata(metadata)
+}
+
+// isSdistPkgInfo сообщает, что entry — <dir>/PKG-INFO в корне sdist.
+func isSdistPkgInfo(entry string) bool {
+	dir, file, ok := strings.Cut(strings.TrimPrefix(entry, "./"), "/")
+	return ok && dir != "" && file == "PKG-INFO"
+}
+
+// readPypiTarMetadata читает PKG-INFO из sdist .tar.gz.
+func readPypiTarMetadata(ctx context.Context, artifact Artifact) ([]byte, error) {
+	body, err := artifact.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, notPackage("not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, notPackage("no <dir>/PKG-INFO in the sdist")
+		}
+		if err != nil {
+			return nil, notPackage("broken tar archive: %v", err)
+		}
+		if isSdistPkgInfo(header.Name) {
+			data, err := io.ReadAll(io.LimitReader(tr, 16<<20))
+			if err != nil {
+				return nil, notPackage("broken tar archive: %v", err)
+			}
+			return data, nil
+		}
+	}
+}
+
+// readPypiZipMetadata читает метаданные Python из wheel, egg или sdist в zip.
+func readPypiZipMetadata(ctx context.Context, artifact Artifact, match func(entry string) bool) ([]byte, error) {
+	data, found, err := readZipEntry(ctx, artifact, match)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, notPackage("no Python metadata (METADATA or PKG-INFO) in the archive")
+	}
+	return data, nil
+}
+
+// parsePypiMetadata разбирает заголовки METADATA/PKG-INFO в формате RFC 822
+// до первой пустой строки, после которой идет описание проекта.
+func parsePypiMetadata(data []byte) (packageInfo, error) {
+	var info packageInfo
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			info.Name = strings.TrimSpace(value)
+		case "version":
+			info.Version = strings.TrimSpace(value)
+		}
+	}
+	if !pypiProjectName.MatchString(info.Name) {
+		return packageInfo{}, notPackage("invalid project name %q in Python metadata", info.Name)
+	}
+	if !pypiVersion.MatchString(info.Version) {
+		return packageInfo{}, notPackage("invalid version %q of %s in Python metadata", info.Version, info.Name)
+	}
+	return info, nil
+}
+
+// PypiExporter сохраняет ассеты как есть и с -simple-index строит локальный индекс PEP 503.
+type PypiExporter struct {
+	DefaultExporter
+}
+
+// IsMetadata сообщает, что файл относится к индексу simple/, который экспорт строит сам.
+func (e *PypiExporter) IsMetadata(relPath string) bool {
+	return strings.HasPrefix(relPath, pypiSimpleDir+"/")
+}
+
+// ExportMetadata строит в exportDir/simple индекс PEP 503 по скачанным дистрибутивам,
+// чтобы pip мог ставить пакеты из директории через --index-url file://.
+func (e *PypiExporter) ExportMetadata(ctx context.Context, opts ExportOptions, exportDir string, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error) {
+	if !opts.SimpleIndex || opts.DryRun {
+		return 0, nil, nil
+	}
+	started := time.Now()
+	indexDir := filepath.Join(exportDir, pypiSimpleDir)
+	projects, err := writePypiSimpleIndex(ctx, exportDir, indexDir)
+	item := ReportItem{Path: indexDir, Result: exportNew.String(), DurationMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		item.Result, item.Error = reportResultFailed, err.Error()
+		opts.Report.Add(item)
+		fmt.Fprintf(os.Stderr, "Ошибка построения индекса %s: %v\n", indexDir, err)
+		return 1, []FailedItem{{Path: indexDir, Err: err}}, nil
+	}
+	opts.Report.Add(item)
+	fmt.Printf("Построен индекс %s: проектов %d\n", indexDir, projects)
+	return 1, nil, nil
+}
+
+// pypiFile — дистрибутив для страницы проекта в индексе simple/.
+type pypiFile struct {
+	RelPath string // путь относительно директории экспорта через "/"
+	Digest  string
+}
+
+// writePypiSimpleIndex записывает simple/index.html со списком проектов и
+// simple/<project>/index.html со ссылками на дистрибутивы. Возвращает число проектов.
+func writePypiSimpleIndex(ctx context.Context, dir, indexDir string) (int, error) {
+	projects := map[string][]pypiFile{}
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filePath == indexDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isPypiDistribution(filePath) {
+			return nil
+		}
+		artifact, err := localArtifact(filePath, dir)
+		if err != nil {
+			return err
+		}
+		dist, err := readPypiDistribution(ctx, artifact)
+		var skip *notPackageError
+		if errors.As(err, &skip) {
+			fmt.Fprintf(os.Stderr, "Пропущен %s: %s\n", artifact.Path, skip.Reason)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		digest, err := fileSHA256(filePath)
+		if err != nil {
+			return err
+		}
+		project := pypiNormalize(dist.Name)
+		projects[project] = append(projects[project], pypiFile{RelPath: artifact.Path, Digest: digest})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.RemoveAll(indexDir); err != nil {
+		return 0, fmt.Errorf("failed to clean %s: %w", indexDir, err)
+	}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var root strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&root, "    <a href=\"%s/\">%s</a>\n", url.PathEscape(name), html.EscapeString(name))
+
+		var page strings.Builder
+		files := projects[name]
+		sort.Slice(files, func(i, j int) bool { return files[i].RelPath < files[j].RelPath })
+		for _, file := range files {
+			href := "../../" + escapeURLPath(file.RelPath) + "#sha256=" + file.Digest
+			fmt.Fprintf(&page, "    <a href=\"%s\">%s</a>\n", html.EscapeString(href), html.EscapeString(path.Base(file.RelPath)))
+		}
+		if err := writeSimplePage(filepath.Join(indexDir, name, "index.html"), "Links for "+name, page.String()); err != nil {
+			return 0, err
+		}
+	}
+	if err := writeSimplePage(filepath.Join(indexDir, "index.html"), "Simple index", root.String()); err != nil {
+		return 0, err
+	}
+	return len(names), nil
+}
+
+// escapeURLPath кодирует каждый сегмент пути для ссылки.
+func escapeURLPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// writeSimplePage записывает HTML-страницу индекса PEP 503.
+func writeSimplePage(pagePath, title, links string) error {
+	if err := os.MkdirAll(filepath.Dir(pagePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	page := fmt.Sprintf("<!DOCTYPE html>\n<html>\n  <head>\n    <meta name=\"pypi:repository-version\" content=\"1.0\">\n    <title>%s</title>\n  </head>\n  <body>\n%s  </body>\n</html>\n",
+		html.EscapeString(title), links)
+	if err := os.WriteFile(pagePath, []byte(page), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", pagePath, err)
+	}
+	return nil
+}
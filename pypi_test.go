@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadPypiDistribution(t *testing.T) {
+	dir := t.TempDir()
+	metadata := "Metadata-Version: 2.1\nName: My.Project\nVersion: 1.0.post1\n\nName: not-a-header\n"
+	tests := []struct {
+		file   string
+		files  map[string]string
+		want   string
+		reason string
+	}{
+		{"my_project-1.0.post1-py3-none-any.whl", map[string]string{
+			"my_project/__init__.py":                  "",
+			"my_project-1.0.post1.dist-info/METADATA": metadata,
+		}, "My.Project@1.0.post1", ""},
+		{"my_project-1.0.post1.zip", map[string]string{"my_project-1.0.post1/PKG-INFO": metadata}, "My.Project@1.0.post1", ""},
+		{"my_project-1.0.post1-py3.8.egg", map[string]string{"EGG-INFO/PKG-INFO": metadata}, "My.Project@1.0.post1", ""},
+		{"my_project-1.0.post1.tar.gz", map[string]string{"my_project-1.0.post1/PKG-INFO": metadata}, "My.Project@1.0.post1", ""},
+		{"site.zip", map[string]string{"index.html": "<html></html>"}, "", "no Python metadata"},
+		{"backup.tar.gz", map[string]string{"backup/data.sql": ""}, "", "no <dir>/PKG-INFO"},
+		{"broken.whl", map[string]string{"broken.dist-info/METADATA": "Name: -bad-\nVersion: 1.0\n"}, "", "invalid project name"},
+	}
+	for _, tt := range tests {
+		archive := filepath.Join(dir, tt.file)
+		if strings.HasSuffix(tt.file, ".tar.gz") {
+			writeTarball(t, archive, tt.files)
+		} else {
+			writeJar(t, archive, tt.files)
+		}
+		info, err := (&PypiUploader{}).Inspect(context.Background(), mustLocalArtifact(t, archive, dir))
+		var skip *notPackageError
+		switch {
+		case tt.reason == "" && (err != nil || info.String() != tt.want):
+			t.Errorf("%s: expected %s, got %s (%v)", tt.file, tt.want, info, err)
+		case tt.reason != "" && (!errors.As(err, &skip) || !strings.Contains(skip.Reason, tt.reason)):
+			t.Errorf("%s: expected skip reason %q, got %v", tt.file, tt.reason, err)
+		}
+	}
+	if got := pypiNormalize("My.Project__Name"); got != "my-project-name" {
+		t.Errorf("Unexpected normalized name %s", got)
+	}
+}
+
+func TestExportPypiSimpleIndex(t *testing.T) {
+	// 1. В репозитории wheel и sdist одного проекта, посторонний архив и страница simple/ самого Nexus
+	srcDir := t.TempDir()
+	files := map[string][]byte{}
+	wheel := filepath.Join(srcDir, "my_project-1.0-py3-none-any.whl")
+	writeJar(t, wheel, map[string]string{"my_project-1.0.dist-info/METADATA": "Name: My_Project\nVersion: 1.0\n"})
+	sdist := filepath.Join(srcDir, "my-project-1.0.tar.gz")
+	writeTarball(t, sdist, map[string]string{"my-project-1.0/PKG-INFO": "Name: my-project\nVersion: 1.0\n"})
+	other := filepath.Join(srcDir, "docs.zip")
+	writeJar(t, other, map[string]string{"index.html": ""})
+	assets := []Asset{}
+	for _, local := range []string{wheel, sdist, other} {
+		data, err := os.ReadFile(local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assetPath := "packages/my-project/1.0/" + filepath.Base(local)
+		files["/repository/pypi-hosted/"+assetPath] = data
+		assets = append(assets, Asset{Path: assetPath})
+	}
+	assets = append(assets, Asset{Path: "simple/my-project/"})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/service/rest/v1/search/assets" {
+			for i := range assets {
+				assets[i].DownloadURL = server.URL + "/repository/pypi-hosted/" + assets[i].Path
+			}
+			json.NewEncoder(w).Encode(SearchResult{Items: assets})
+			return
+		}
+		if data, ok := files[r.URL.Path]; ok {
+			w.Write(data)
+			return
+		}
+		w.Write([]byte("<html>nexus</html>"))
+	}))
+	defer server.Close()
+
+	// 2. С -simple-index экспорт строит simple/ по скачанным дистрибутивам с относительными ссылками
+	dir := chdirTemp(t)
+	err := ExportFiles(context.Background(), ExportOptions{RepoURL: server.URL, RepoName: "pypi-hosted", RepoType: "pypi", SimpleIndex: true, Workers: 2, QueueSize: 10})
+	if err != nil {
+		t.Fatalf("ExportFiles failed: %v", err)
+	}
+	root, err := os.ReadFile(filepath.Join(dir, "pypi-hosted", "simple", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(root), `<a href="my-project/">my-project</a>`) {
+		t.Errorf("Unexpected simple/index.html:\n%s", root)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "pypi-hosted", "simple", "my-project", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wheelLink := fmt.Sprintf(`href="../../packages/my-project/1.0/my_project-1.0-py3-none-any.whl#sha256=%x"`, sha256.Sum256(files["/repository/pypi-hosted/packages/my-project/1.0/my_project-1.0-py3-none-any.whl"]))
+	if !strings.Contains(string(page), wheelLink) || !strings.Contains(string(page), "my-project-1.0.tar.gz") {
+		t.Errorf("Unexpected simple/my-project/index.html:\n%s", page)
+	}
+	// 3. Посторонний архив в индекс не попал, а страница Nexus не скачивалась
+	if strings.Contains(string(page), "docs.zip") || strings.Contains(string(page), "nexus") {
+		t.Errorf("Unexpected links in simple/my-project/index.html:\n%s", page)
+	}
+}
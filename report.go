@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Форматы отчета о запуске.
+const (
+	reportJSON  = "json"
+	reportJUnit = "junit"
+)
+
+// reportResultFailed — итог файла, обработка которого завершилась ошибкой.
+const reportResultFailed = "failed"
+
+// ReportItem — строка отчета об одном файле.
+type ReportItem struct {
+	Path       string `json:"path"`
+	URL        string `json:"url,omitempty"`
+	Result     string `json:"result"`            // new, updated, skipped, uploaded, present, tagged, migrated, copied, deleted, unsupported или failed
+	Package    string `json:"package,omitempty"` // name@version, если пакет прочитан из файла
+	Reason     string `json:"reason,omitempty"`  // почему файл пропущен
+	Bytes      int64  `json:"bytes"`
+	DurationMs int64  `json:"durationMs"`
+	HTTPStatus int    `json:"httpStatus,omitempty"` // статус последнего ответа Nexus
+	Attempts   int    `json:"attempts,omitempty"`   // число попыток запроса с учетом повторов
+	Error      string `json:"error,omitempty"`
+}
+
+// ReportTotals — итоги запуска.
+type ReportTotals struct {
+	Files      int   `json:"files"`
+	Succeeded  int   `json:"succeeded"`
+	Failed     int   `json:"failed"`
+	Skipped    int   `json:"skipped"`
+	Bytes      int64 `json:"bytes"`
+	DurationMs int64 `json:"durationMs"`
+}
+
+// Report собирает результаты по файлам для CI. Методы безопасны для вызова
+// из нескольких воркеров; у nil-отчета они ничего не делают.
+type Report struct {
+	Action   string       `json:"action"`
+	RepoURL  string       `json:"repoUrl"`
+	RepoName string       `json:"repoName"`
+	DryRun   bool         `json:"dryRun,omitempty"`
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Error    string       `json:"error,omitempty"` // ошибка запуска в целом, например листинга
+	Totals   ReportTotals `json:"totals"`
+	Items    []ReportItem `json:"items"`
+
+	mu sync.Mutex
+}
+
+// newReport начинает отчет о запуске.
+func newReport(action, repoURL, repoName string, dryRun bool) *Report {
+	return &Report{
+		Action:   action,
+		RepoURL:  repoURL,
+		RepoName: repoName,
+		DryRun:   dryRun,
+		Started:  time.Now().UTC(),
+		Items:    []ReportItem{},
+	}
+}
+
+// Add добавляет строку отчета.
+func (r *Report) Add(item ReportItem) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Items = append(r.Items, item)
+}
+
+// Finish подводит итоги и запоминает ошибку запуска.
+func (r *Report) Finish(runErr error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Finished = time.Now().UTC()
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	r.Totals = ReportTotals{Files: len(r.Items), DurationMs: r.Finished.Sub(r.Started).Milliseconds()}
+	for _, item := range r.Items {
+		r.Totals.Bytes += item.Bytes
+		switch {
+		case item.Result == reportResultFailed:
+			r.Totals.Failed++
+		case isSkippedResult(item.Result):
+			r.Totals.Skipped++
+		default:
+			r.Totals.Succeeded++
+		}
+	}
+}
+
+func isSkippedResult(result string) bool {
+	return result == "skipped" || result == "unsupported" || result == importPresent
+}
+
+// WriteFile сохраняет отчет в формате json или junit.
+func (r *Report) WriteFile(path, format string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	if err := r.write(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+func (r *Report) write(w io.Writer, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch format {
+	case reportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	case reportJUnit:
+		if _, err := io.WriteString(w, xml.Header); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		encoder := xml.NewEncoder(w)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(r.junit()); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown report format %q, use '%s' or '%s'", format, reportJSON, reportJUnit)
+	}
+	return nil
+}
+
+// Структуры JUnit XML: каждый файл — отдельный testcase, чтобы GitLab и Jenkins
+// показывали упавшие артефакты поштучно.
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func (r *Report) junit() junitTestSuites {
+	suite := junitTestSuite{
+		Name:      fmt.Sprintf("nexus-operator %s %s", r.Action, r.RepoName),
+		Tests:     r.Totals.Files,
+		Failures:  r.Totals.Failed,
+		Skipped:   r.Totals.Skipped,
+		Time:      junitSeconds(r.Totals.DurationMs),
+		Timestamp: r.Started.Format("2006-01-02T15:04:05"),
+	}
+	// Ошибка листинга не привязана к файлу, но тоже должна уронить сборку.
+	if r.Error != "" {
+		suite.Failures++
+		suite.Tests++
+		suite.Cases = append(suite.Cases, junitTestCase{
+			ClassName: r.RepoName,
+			Name:      r.Action,
+			Time:      "0",
+			Failure:   &junitFailure{Message: r.Error, Text: r.Error},
+		})
+	}
+	for _, item := range r.Items {
+		testCase := junitTestCase{
+			ClassName: r.RepoName,
+			Name:      item.Path,
+			Time:      junitSeconds(item.DurationMs),
+			SystemOut: fmt.Sprintf("result=%s url=%s bytes=%d httpStatus=%d attempts=%d",
+				item.Result, item.URL, item.Bytes, item.HTTPStatus, item.Attempts),
+		}
+		switch {
+		case item.Result == reportResultFailed:
+			testCase.Failure = &junitFailure{Message: item.Error, Text: item.Error}
+		case isSkippedResult(item.Result):
+			testCase.Skipped = &junitSkipped{Message: item.Result}
+			if item.Reason != "" {
+				testCase.Skipped.Message += ": " + item.Reason
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	return junitTestSuites{Suites: []junitTestSuite{suite}}
+}
+
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// requestStats собирает сведения о запросах к Nexus, выполненных для одного файла:
+// контекст задачи несет их через retry и загрузчики до sendNexusRequest.
+type requestStats struct {
+	mu       sync.Mutex
+	url      string
+	status   int
+	attempts map[string]int
+	bytes    int64
+}
+
+type requestStatsKey struct{}
+
+// trackRequests возвращает контекст, запросы в котором записываются в requestStats.
+func trackRequests(ctx context.Context) (context.Context, *requestStats) {
+	stats := &requestStats{attempts: map[string]int{}}
+	return context.WithValue(ctx, requestStatsKey{}, stats), stats
+}
+
+// requestStatsFrom возвращает статистику задачи или nil, если отчет не ведется.
+func requestStatsFrom(ctx context.Context) *requestStats {
+	stats, _ := ctx.Value(requestStatsKey{}).(*requestStats)
+	return stats
+}
+
+// observe записывает попытку запроса и полученный статус.
+func (s *requestStats) observe(method, url string, status int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.url = url
+	s.attempts[method+" "+url]++
+	if status != 0 {
+		s.status = status
+	}
+}
+
+// setBytes запоминает объем переданных данных; повторная попытка начинает счет заново.
+func (s *requestStats) setBytes(n int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.bytes = n
+	s.mu.Unlock()
+}
+
+func (s *requestStats) addBytes(n int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.bytes += n
+	s.mu.Unlock()
+}
+
+// item превращает статистику в строку отчета. url — адрес ассета, если он известен
+// заранее; иначе берется адрес последнего запроса.
+func (s *requestStats) item(path, url, result string, started time.Time, err error) ReportItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := ReportItem{
+		Path:       path,
+		URL:        url,
+		Result:     result,
+		Bytes:      s.bytes,
+		DurationMs: time.Since(started).Milliseconds(),
+		HTTPStatus: s.status,
+	}
+	if item.URL == "" {
+		item.URL = s.url
+	}
+	// Попытки считаются по каждому запросу отдельно, в отчет идет наибольшее число.
+	for _, n := range s.attempts {
+		if n > item.Attempts {
+			item.Attempts = n
+		}
+	}
+	if err != nil {
+		item.Result = reportResultFailed
+		item.Error = err.Error()
+	}
+	return item
+}
+
+// countingReadCloser сообщает requestStats о каждом прочитанном блоке тела запроса.
+type countingReadCloser struct {
+	io.ReadCloser
+	stats *requestStats
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.stats.addBytes(int64(n))
+	return n, err
+}
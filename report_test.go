@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExportFilesReport(t *testing.T) {
+	fastRetries(t, 3)
+
+	// 1. ok.txt отдается после одного 503, missing.txt отсутствует
+	var okRequests int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/service/rest/v1/search/assets":
+			json.NewEncoder(w).Encode(SearchResult{Items: []Asset{
+				{DownloadURL: server.URL + "/repository/test-repo/ok.txt", Path: "ok.txt"},
+				{DownloadURL: server.URL + "/repository/test-repo/missing.txt", Path: "missing.txt"},
+			}})
+		case "/repository/test-repo/ok.txt":
+			if atomic.AddInt32(&okRequests, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("hello world"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := chdirTemp(t)
+	report := newReport("export", server.URL, "test-repo", false)
+	err := ExportFiles(context.Background(), ExportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "test-repo",
+		RepoType:  "raw",
+		Workers:   2,
+		QueueSize: 10,
+		Report:    report,
+	})
+	if err == nil {
+		t.Fatal("Expected export to fail for missing.txt")
+	}
+	report.Finish(err)
+
+	// 2. В отчете по строке на файл со статусом, попытками и объемом
+	items := map[string]ReportItem{}
+	for _, item := range report.Items {
+		items[item.Path] = item
+	}
+	ok := items[filepath.Join("test-repo", "ok.txt")]
+	if ok.Result != "new" || ok.HTTPStatus != http.StatusOK || ok.Attempts != 2 || ok.Bytes != 11 {
+		t.Errorf("Unexpected report item for ok.txt: %+v", ok)
+	}
+	if ok.URL != server.URL+"/repository/test-repo/ok.txt" {
+		t.Errorf("Expected asset URL in report, got %q", ok.URL)
+	}
+	missing := items[filepath.Join("test-repo", "missing.txt")]
+	if missing.Result != reportResultFailed || missing.HTTPStatus != http.StatusNotFound || missing.Error == "" {
+		t.Errorf("Unexpected report item for missing.txt: %+v", missing)
+	}
+	if report.Totals.Files != 2 || report.Totals.Succeeded != 1 || report.Totals.Failed != 1 || report.Totals.Bytes != 11 {
+		t.Errorf("Unexpected totals: %+v", report.Totals)
+	}
+
+	// 3. JUnit XML содержит failure для упавшего файла
+	junitPath := filepath.Join(dir, "report.xml")
+	if err := report.WriteFile(junitPath, reportJUnit); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, err := os.ReadFile(junitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("Invalid JUnit XML: %v", err)
+	}
+	// Один testcase на файл и один на ошибку запуска в целом.
+	suite := suites.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 2 {
+		t.Errorf("Expected 3 tests with 2 failures, got %d/%d", suite.Tests, suite.Failures)
+	}
+	failed := 0
+	for _, testCase := range suite.Cases {
+		if testCase.Failure != nil {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Errorf("Expected 2 failed test cases, got %d", failed)
+	}
+}
+
+func TestImportFilesReportMultipart(t *testing.T) {
+	fastRetries(t, 3)
+
+	// 1. Первая загрузка через components API получает 503, повтор проходит
+	var uploads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&uploads, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	rpm := filepath.Join(dir, "tool-1.0-1.x86_64.rpm")
+	if err := os.WriteFile(rpm, []byte("rpm payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := newReport("import", server.URL, "yum-hosted", false)
+	err := ImportFiles(context.Background(), ImportOptions{
+		RepoURL:   server.URL,
+		RepoName:  "yum-hosted",
+		RepoType:  "yum",
+		ImportDir: dir,
+		Workers:   1,
+		Journal:   filepath.Join(t.TempDir(), "journal.jsonl"),
+		Report:    report,
+	})
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+
+	// 2. В отчете объем файла из последней попытки, без заголовков формы
+	if len(report.Items) != 1 {
+		t.Fatalf("Expected 1 report item, got %+v", report.Items)
+	}
+	item := report.Items[0]
+	if item.Result != "uploaded" || item.Attempts != 2 || item.Bytes != int64(len("rpm payload")) {
+		t.Errorf("Unexpected report item: %+v", item)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/tabwriter"
+)
+
+// Repository — описание репозитория из /service/rest/v1/repositories.
+type Repository struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Type   string `json:"type"` // hosted, proxy или group
+	URL    string `json:"url"`
+}
+
+// repoFormats сопоставляет форматы Nexus со значениями -repo-type там, где они расходятся.
+var repoFormats = map[string]string{
+	"maven2": "maven",
+}
+
+// repoTypeFor возвращает значение -repo-type для формата репозитория Nexus.
+func repoTypeFor(format string) string {
+	if repoType, ok := repoFormats[format]; ok {
+		return repoType
+	}
+	return format
+}
+
+func fetchRepositories(ctx context.Context, repoURL string, auth Auth) ([]Repository, error) {
+	resp, err := executeNexusRequest(ctx, "GET", repoURL+"/service/rest/v1/repositories", "", nil, auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch repositories: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("failed to fetch repositories", resp)
+	}
+
+	var repos []Repository
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return repos, nil
+}
+
+// detectRepoType определяет -repo-type по формату репозитория, чтобы его не приходилось
+// указывать вручную. Список репозиториев виден не каждой учетной записи, поэтому
+// вызывающий решает сам, критична ли ошибка.
+func detectRepoType(ctx context.Context, repoURL, repoName string, auth Auth) (string, error) {
+	repos, err := fetchRepositories(ctx, repoURL, auth)
+	if err != nil {
+		return "", err
+	}
+	for _, repo := range repos {
+		if repo.Name == repoName {
+			return repoTypeFor(repo.Format), nil
+		}
+	}
+	return "", fmt.Errorf("%w: repository %s at %s", ErrNotFound, repoName, repoURL)
+}
+
+// ListRepositories печатает репозитории экземпляра Nexus таблицей: имя, тип для
+// -repo-type, вид (hosted/proxy/group) и URL.
+func ListRepositories(ctx context.Context, repoURL string, auth Auth, out io.Writer) error {
+	repos, err := fetchRepositories(ctx, repoURL, auth)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREPO-TYPE\tKIND\tURL")
+	for _, repo := range repos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", repo.Name, repoTypeFor(repo.Format), repo.Type, repo.URL)
+	}
+	return w.Flush()
+}
+
+// ListAssets печатает ассеты репозитория по мере получения страниц: путь, размер и sha1.
+// Размер неизвестен старым версиям Nexus и выводится как "-".
+func ListAssets(ctx context.Context, repoURL, repoName string, auth Auth, out io.Writer) error {
+	return walkAssets(ctx, repoURL, repoName, "", auth, func(items []Asset, _ string) error {
+		for _, asset := range items {
+			size := "-"
+			if asset.FileSize > 0 {
+				size = fmt.Sprint(asset.FileSize)
+			}
+			sha1 := asset.Checksum["sha1"]
+			if sha1 == "" {
+				sha1 = "-"
+			}
+			if _, err := fmt.Fprintf(out, "%s\t%s\t%s\n", asset.Path, size, sha1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
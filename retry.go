@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy задает повторные попытки для запросов к Nexus.
+type RetryPolicy struct {
+	MaxAttempts int           // общее число попыток, включая первую
+	BaseDelay   time.Duration // задержка перед второй попыткой, дальше удваивается
+	MaxDelay    time.Duration // верхняя граница задержки, в том числе для Retry-After
+}
+
+// retryPolicy используется всеми запросами к Nexus. main переопределяет его значениями флагов.
+var retryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// retryableError помечает временную ошибку, после которой запрос имеет смысл повторить.
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // задержка, которую попросил сервер через Retry-After
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Do выполняет op, повторяя ее после временных ошибок с экспоненциальной
+// задержкой и джиттером. Постоянные ошибки возвращаются сразу, отмена ctx
+// прерывает ожидание между попытками.
+func (p RetryPolicy) Do(ctx context.Context, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(p.delay(attempt, retryable.retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// delay вычисляет паузу перед следующей попыткой.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return retryAfter
+	}
+
+	backoff := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || backoff < p.MaxDelay); i++ {
+		backoff *= 2
+	}
+	if p.MaxDelay > 0 && backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	// Джиттер в пределах [backoff/2, backoff], чтобы воркеры не повторяли запросы синхронно.
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryableStatus сообщает, является ли HTTP-статус временной ошибкой.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableNetError сообщает, является ли сетевая ошибка временной:
+// таймаут, сброс или обрыв соединения.
+func isRetryableNetError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryableStatusError превращает временный HTTP-статус в ошибку для повтора с учетом Retry-After.
+func retryableStatusError(resp *http.Response) error {
+	return &retryableError{
+		err:        fmt.Errorf("server returned %s", resp.Status),
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After: число секунд или HTTP-дату.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetries уменьшает задержки между попытками на время теста.
+func fastRetries(t *testing.T, maxAttempts int) {
+	t.Helper()
+	saved := retryPolicy
+	retryPolicy = RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	t.Cleanup(func() { retryPolicy = saved })
+}
+
+func TestExecuteNexusRequestRetries(t *testing.T) {
+	fastRetries(t, 5)
+
+	// 1. Первые два запроса получают 503, третий — успех
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := executeNexusRequest(context.Background(), "GET", server.URL, "", nil, nil)
+	if err != nil {
+		t.Fatalf("executeNexusRequest failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestExecuteNexusRequestGivesUp(t *testing.T) {
+	fastRetries(t, 3)
+
+	// 1. Сервер всегда отвечает 429: после исчерпания попыток возвращается последний ответ
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	resp, err := executeNexusRequest(context.Background(), "GET", server.URL, "", nil, nil)
+	if err != nil {
+		t.Fatalf("executeNexusRequest failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", resp.StatusCode)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestUploadRetryResendsFile(t *testing.T) {
+	fastRetries(t, 3)
+
+	// 1. Первая попытка получает 502; обе попытки должны прислать файл целиком
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "hello world" {
+			t.Errorf("Expected body 'hello world', got '%s'", string(body))
+		}
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	importDir := t.TempDir()
+	filePath := filepath.Join(importDir, "test-file.txt")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := uploadFileRaw(context.Background(), server.URL, "test-raw", mustLocalArtifact(t, filePath, importDir), nil, false); err != nil {
+		t.Fatalf("uploadFileRaw failed: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func TestDownloadFileRetriesInterrupted(t *testing.T) {
+	fastRetries(t, 3)
+
+	// 1. Первый ответ обрывается на середине, второй приходит целиком
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("hello"))
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "file.txt")
+	if err := downloadFile(context.Background(), server.URL, destination, nil, nil, false); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	content, err := os.ReadFile(destination)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if string(content) != "hello world" {
+		t.Errorf("Expected content 'hello world', got '%s'", string(content))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("5"); got != 5*time.Second {
+		t.Errorf("Expected 5s, got %v", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("Expected 0 for empty header, got %v", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 0 || got > time.Minute {
+		t.Errorf("Expected delay within a minute for HTTP date, got %v", got)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 8; attempt++ {
+		d := p.delay(attempt, 0)
+		if d > p.MaxDelay {
+			t.Errorf("Attempt %d: delay %v exceeds max delay", attempt, d)
+		}
+	}
+	if d := p.delay(1, time.Hour); d != p.MaxDelay {
+		t.Errorf("Expected Retry-After to be capped at %v, got %v", p.MaxDelay, d)
+	}
+}
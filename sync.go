@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Направления синхронизации относительно репозитория -repo-name.
+const (
+	syncFromRepo = "from-repo" // репозиторий — эталон, другая сторона догоняет его
+	syncToRepo   = "to-repo"   // другая сторона — эталон, репозиторий догоняет ее
+)
+
+// SyncOptions задает синхронизацию: стороны те же, что у diff, плюс направление.
+type SyncOptions struct {
+	DiffOptions
+
+	Direction string // syncFromRepo или syncToRepo
+	Delete    bool   // удалять на принимающей стороне то, чего нет у эталона
+	DryRun    bool
+
+	Report *Report // отчет по файлам; nil — не вести
+}
+
+// syncResult — итог применения одного расхождения.
+type syncResult struct {
+	Path    string
+	Copied  bool
+	Deleted bool
+	Skipped bool // формат не поддерживается загрузчиком принимающего репозитория
+	Err     error
+}
+
+// outcome возвращает итог для отчета.
+func (r syncResult) outcome() string {
+	switch {
+	case r.Skipped:
+		return "unsupported"
+	case r.Deleted:
+		return "deleted"
+	}
+	return "copied"
+}
+
+// SyncRepository сравнивает стороны так же, как DiffRepository, и устраняет
+// расхождения в выбранном направлении: копирует отсутствующие и отличающиеся
+// файлы, а с Delete еще и удаляет лишние. Журнал не ведется: повторный запуск
+// заново сравнивает стороны и продолжает с того, что еще не совпадает.
+func SyncRepository(ctx context.Context, opts SyncOptions) error {
+	if opts.Direction != syncFromRepo && opts.Direction != syncToRepo {
+		return fmt.Errorf("unknown sync direction %q, use '%s' or '%s'", opts.Direction, syncFromRepo, syncToRepo)
+	}
+
+	// Загрузчик нужен, только если принимающая сторона — репозиторий Nexus.
+	var uploader Uploader
+	if opts.Direction == syncToRepo || opts.comparesRepos() {
+		var ok bool
+		uploader, ok = GetUploader(opts.RepoType)
+		if !ok {
+			return fmt.Errorf("неподдерживаемый тип репозитория: %s", opts.RepoType)
+		}
+	}
+
+	entries, _, err := compareSides(ctx, opts.DiffOptions)
+	if err != nil {
+		return err
+	}
+
+	var actions []diffEntry
+	for _, entry := range entries {
+		if entry.Kind == diffDifferent || opts.Delete || entry.Kind == copyKind(opts.Direction) {
+			actions = append(actions, entry)
+		}
+	}
+	if len(actions) == 0 {
+		fmt.Println("Стороны совпадают, синхронизировать нечего.")
+		return nil
+	}
+
+	bar := progressbar.NewOptions(len(actions),
+		progressbar.OptionSetDescription("Syncing"),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	tasks := make(chan diffEntry)
+	results := make(chan syncResult, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 1; w <= workers; w++ {
+		go func() {
+			defer wg.Done()
+			for entry := range tasks {
+				if ctx.Err() != nil {
+					continue
+				}
+				taskCtx, stats := trackRequests(ctx)
+				started := time.Now()
+				result := syncEntry(taskCtx, uploader, entry, opts)
+				result.Path = entry.Key
+				if result.Err != nil && ctx.Err() != nil {
+					continue // прервано отменой, а не ошибкой
+				}
+				opts.Report.Add(stats.item(entry.Key, "", result.outcome(), started, result.Err))
+				results <- result
+				bar.Add(1)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(tasks)
+		for _, entry := range actions {
+			if ctx.Err() != nil {
+				return
+			}
+			tasks <- entry
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	copied, deleted, skipped := 0, 0, 0
+	var failed []FailedItem
+	for result := range results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(os.Stderr, "Ошибка синхронизации: %v\n", result.Err)
+			failed = append(failed, FailedItem{Path: result.Path, Err: result.Err})
+		case result.Skipped:
+			skipped++
+		case result.Copied:
+			copied++
+		case result.Deleted:
+			deleted++
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Printf("Синхронизация прервана: скопировано %d, удалено %d, с ошибками: %d\n", copied, deleted, len(failed))
+		fmt.Println("Запустите синхронизацию повторно, чтобы продолжить.")
+		return fmt.Errorf("sync interrupted: %w", ctx.Err())
+	}
+
+	if opts.DryRun {
+		fmt.Printf("[Dry Run] Было бы скопировано %d файлов, удалено %d, пропущено неподдерживаемых: %d.\n", copied, deleted, skipped)
+	} else {
+		fmt.Printf("Скопировано файлов: %d, удалено: %d, пропущено неподдерживаемых: %d, с ошибками: %d\n",
+			copied, deleted, skipped, len(failed))
+	}
+
+	return partialFailure("файлов не удалось синхронизировать", len(actions), failed)
+}
+
+// copyKind возвращает вид расхождения, который устраняется копированием:
+// при синхронизации из репозитория копируются отсутствующие на другой стороне
+// ассеты, в репозиторий — лишние файлы другой стороны. Противоположный вид
+// устраняется удалением.
+func copyKind(direction string) diffKind {
+	if direction == syncToRepo {
+		return diffExtra
+	}
+	return diffMissing
+}
+
+// syncEntry устраняет одно расхождение.
+func syncEntry(ctx context.Context, uploader Uploader, entry diffEntry, opts SyncOptions) syncResult {
+	var err error
+	result := syncResult{}
+	switch {
+	case entry.Kind != diffDifferent && entry.Kind != copyKind(opts.Direction):
+		result.Deleted = true
+		err = syncDelete(ctx, entry, opts)
+	case opts.Direction == syncFromRepo && opts.comparesRepos():
+		if !uploader.IsSupported(entry.Key) {
+			return syncResult{Skipped: true}
+		}
+		result.Copied = true
+		artifact := remoteArtifact(assetTask(*entry.Repo, entry.Key), opts.Auth)
+		err = uploader.Upload(ctx, opts.TargetURL, opts.TargetRepo, artifact, opts.TargetAuth, opts.DryRun)
+	case opts.Direction == syncFromRepo:
+		result.Copied = true
+		task := assetTask(*entry.Repo, filepath.Join(opts.LocalDir, filepath.FromSlash(entry.Key)))
+		err = exportAsset(ctx, task, opts.Auth, opts.DryRun, false).Err
+	case opts.comparesRepos():
+		if !uploader.IsSupported(entry.Key) {
+			return syncResult{Skipped: true}
+		}
+		result.Copied = true
+		artifact := remoteArtifact(assetTask(*entry.Other.Asset, entry.Key), opts.TargetAuth)
+		err = uploader.Upload(ctx, opts.RepoURL, opts.RepoName, artifact, opts.Auth, opts.DryRun)
+	default:
+		if !uploader.IsSupported(entry.Other.LocalPath) {
+			return syncResult{Skipped: true}
+		}
+		imported := importFile(ctx, uploader, entry.Other.LocalPath, ImportOptions{
+			RepoURL:   opts.RepoURL,
+			RepoName:  opts.RepoName,
+			ImportDir: opts.LocalDir,
+			Auth:      opts.Auth,
+			DryRun:    opts.DryRun,
+		})
+		if imported.Outcome == "unsupported" {
+			return syncResult{Skipped: true}
+		}
+		result.Copied = true
+		err = imported.Err
+	}
+	if err != nil {
+		return syncResult{Err: fmt.Errorf("%s: %w", entry.Key, err)}
+	}
+	return result
+}
+
+// syncDelete удаляет файл, которого нет у эталонной стороны.
+func syncDelete(ctx context.Context, entry diffEntry, opts SyncOptions) error {
+	if opts.DryRun {
+		return nil
+	}
+	switch {
+	case opts.Direction == syncToRepo:
+		return deleteAsset(ctx, opts.RepoURL, *entry.Repo, opts.Auth)
+	case opts.comparesRepos():
+		return deleteAsset(ctx, opts.TargetURL, *entry.Other.Asset, opts.TargetAuth)
+	default:
+		return os.Remove(entry.Other.LocalPath)
+	}
+}
+
+// deleteAsset удаляет ассет через REST API Nexus.
+func deleteAsset(ctx context.Context, repoURL string, asset Asset, auth Auth) error {
+	if asset.ID == "" {
+		return fmt.Errorf("asset has no id, cannot delete it")
+	}
+
+	apiURL := fmt.Sprintf("%s/service/rest/v1/assets/%s", repoURL, asset.ID)
+	resp, err := executeNexusRequest(ctx, "DELETE", apiURL, "", nil, auth)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return statusError("failed to delete asset, status", resp)
+	}
+	return nil
+}
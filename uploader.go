@@ -1,146 +1,136 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
-	"time"
 )
 
-func uploadFileMaven(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+// uploadFileMaven кладет файл по его пути в раскладке Maven и досылает
+// недостающие на диске контрольные суммы md5 и sha1.
+func uploadFileMaven(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	// Координаты определяются и в режиме dry-run, чтобы заранее показать файлы, которые не удастся загрузить.
+	nexusPath, err := mavenRepositoryPath(artifact)
+	if err != nil {
+		return err
+	}
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
+	apiURL := fmt.Sprintf("%s/repository/%s/%s", repoURL, repoName, nexusPath)
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+	if err := putMavenFile(ctx, apiURL, artifactBody(ctx, artifact), auth); err != nil {
+		return err
 	}
-	defer file.Close()
+	return uploadMavenChecksums(ctx, apiURL, artifact, auth)
+}
 
-	relativePath := strings.TrimPrefix(filePath, importDir+"/")
-	if relativePath == filePath {
-		return fmt.Errorf("file path does not start with '%s/': %s", importDir, filePath)
+// uploadFileMavenComponent загружает файл через components API с координатами из POM или пути.
+// В отличие от PUT, Nexus при этом сам обновляет maven-metadata.xml и контрольные суммы.
+func uploadFileMavenComponent(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	coords, err := resolveMavenCoordinates(artifact)
+	if err != nil {
+		return err
 	}
-
-	parts := strings.Split(relativePath, "/")
-	if len(parts) < 4 {
-		return fmt.Errorf("invalid file path for Maven repository: %s", relativePath)
+	if dryRun {
+		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
+		return nil
 	}
 
-	groupID := strings.Join(parts[:len(parts)-3], "/")
-	artifactID := parts[len(parts)-3]
-	version := parts[len(parts)-2]
-	fileName := parts[len(parts)-1]
-
-	nexusPath := fmt.Sprintf("%s/%s/%s/%s", groupID, artifactID, version, fileName)
-	apiURL := fmt.Sprintf("%s/repository/%s/%s", repoURL, repoName, nexusPath)
+	fields := []formField{
+		{"maven2.groupId", coords.GroupID},
+		{"maven2.artifactId", coords.ArtifactID},
+		{"maven2.version", coords.Version},
+		{"maven2.asset1.extension", coords.Extension},
+	}
+	if coords.Classifier != "" {
+		fields = append(fields, formField{"maven2.asset1.classifier", coords.Classifier})
+	}
 
-	resp, err := executeNexusRequest("PUT", apiURL, "application/octet-stream", file, username, password)
+	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
+	resp, err := executeMultipartUpload(ctx, apiURL, "maven2.asset1", artifact, auth, fields...)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error response from Nexus: %s\n", string(responseBody))
-		return fmt.Errorf("failed to upload file: %s", resp.Status)
+	if resp.StatusCode != http.StatusNoContent {
+		return statusErrorBody("failed to upload file", resp)
 	}
 
 	return nil
 }
 
-func uploadFileNpm(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	if dryRun {
-		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
-		return nil
+// mavenRepositoryPath возвращает путь артефакта в репозитории Maven: group/artifact/version/file.
+// maven-metadata.xml не содержит координат файла, поэтому кладется по своему пути в раскладке.
+func mavenRepositoryPath(artifact Artifact) (string, error) {
+	if isMavenMetadata(artifact.Path) {
+		if !isMavenLayoutFile(artifact.Path) {
+			return "", fmt.Errorf("invalid file path for Maven repository: %s", artifact.Path)
+		}
+		return artifact.Path, nil
 	}
-
-	file, err := os.Open(filePath)
+	coords, err := resolveMavenCoordinates(artifact)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return "", err
 	}
-	defer file.Close()
+	return coords.Path(), nil
+}
 
-	relativePath := strings.TrimPrefix(filePath, importDir+"/")
-	if relativePath == filePath {
-		return fmt.Errorf("file path does not start with '%s/': %s", importDir, filePath)
+func uploadFileNpm(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	if dryRun {
+		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
+		return nil
 	}
 
-	parts := strings.Split(relativePath, "/")
-	fileName := parts[len(parts)-1]
 	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
 
-	resp, err := executeMultipartUpload(apiURL, "npm.asset", fileName, file, username, password)
+	resp, err := executeMultipartUpload(ctx, apiURL, "npm.asset", artifact, auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		fmt.Printf("Error response from Nexus: %s\n", string(responseBody))
-		return fmt.Errorf("failed to upload file: %s", resp.Status)
+		return statusErrorBody("failed to upload file", resp)
 	}
 
 	return nil
 }
 
-func uploadFileRaw(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFileRaw(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
-	// Используем filepath.ToSlash для корректной работы на Windows
-	relativePath := filepath.ToSlash(strings.TrimPrefix(filePath, importDir+string(filepath.Separator)))
-	if relativePath == filePath {
-		return fmt.Errorf("file path does not start with '%s/': %s", importDir, filePath)
-	}
-
-	apiURL := fmt.Sprintf("%s/repository/%s/%s", repoURL, repoName, relativePath)
+	apiURL := fmt.Sprintf("%s/repository/%s/%s", repoURL, repoName, artifact.Path)
 
-	resp, err := executeNexusRequest("PUT", apiURL, "application/octet-stream", file, username, password)
+	resp, err := executeNexusRequest(ctx, "PUT", apiURL, "application/octet-stream", artifactBody(ctx, artifact), auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to upload file, status: %s", resp.Status)
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-func uploadFilePypi(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFilePypi(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
 	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
 
-	resp, err := executeMultipartUpload(apiURL, "pypi.asset", filepath.Base(filePath), file, username, password)
+	resp, err := executeMultipartUpload(ctx, apiURL, "pypi.asset", artifact, auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -148,29 +138,26 @@ func uploadFilePypi(repoURL, repoName, filePath, importDir, username, password s
 
 	// PyPI upload returns 204 No Content on success
 	if resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file, status: %s, body: %s", resp.Status, string(responseBody))
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-func uploadFileNuget(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFileNuget(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
 	// NuGet packages are uploaded to the root of the repository endpoint.
 	// The trailing slash is important.
 	apiURL := fmt.Sprintf("%s/repository/%s/", repoURL, repoName)
+	return pushNugetPackage(ctx, apiURL, artifact, auth)
+}
 
-	resp, err := executeNexusRequest("PUT", apiURL, "application/octet-stream", file, username, password)
+// pushNugetPackage публикует пакет или пакет символов NuGet по адресу apiURL.
+func pushNugetPackage(ctx context.Context, apiURL string, artifact Artifact, auth Auth) error {
+	resp, err := executeNexusRequest(ctx, "PUT", apiURL, "application/octet-stream", artifactBody(ctx, artifact), auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -178,110 +165,165 @@ func uploadFileNuget(repoURL, repoName, filePath, importDir, username, password
 
 	// NuGet upload returns 201 Created on success.
 	if resp.StatusCode != http.StatusCreated {
-		responseBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file, status: %s, body: %s", resp.Status, string(responseBody))
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-func uploadFileHelm(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFileHelm(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
 	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
 
-	resp, err := executeMultipartUpload(apiURL, "helm.asset", filepath.Base(filePath), file, username, password)
+	resp, err := executeMultipartUpload(ctx, apiURL, "helm.asset", artifact, auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file, status: %s, body: %s", resp.Status, string(responseBody))
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-func uploadFileYum(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFileYum(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
 	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
 
-	resp, err := executeMultipartUpload(apiURL, "yum.asset", filepath.Base(filePath), file, username, password)
+	resp, err := executeMultipartUpload(ctx, apiURL, "yum.asset", artifact, auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file, status: %s, body: %s", resp.Status, string(responseBody))
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-func uploadFileApt(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
+func uploadFileApt(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
 	if dryRun {
 		// В режиме dry-run просто выходим, прогресс-бар покажет инкремент.
 		return nil
 	}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
-
 	apiURL := fmt.Sprintf("%s/service/rest/v1/components?repository=%s", repoURL, repoName)
 
-	resp, err := executeMultipartUpload(apiURL, "apt.asset", filepath.Base(filePath), file, username, password)
+	resp, err := executeMultipartUpload(ctx, apiURL, "apt.asset", artifact, auth)
 	if err != nil {
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		responseBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to upload file, status: %s, body: %s", resp.Status, string(responseBody))
+		return statusErrorBody("failed to upload file, status", resp)
 	}
 	return nil
 }
 
-// executeMultipartUpload создает и выполняет multipart/form-data запрос.
-func executeMultipartUpload(apiURL, assetKey, fileName string, file io.Reader, username, password string) (*http.Response, error) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+// bodySource открывает тело запроса заново для каждой попытки,
+// чтобы повтор после временной ошибки отправил файл целиком.
+// Второе значение — длина тела в байтах или -1, если она неизвестна.
+type bodySource func() (io.ReadCloser, int64, error)
+
+// formField — текстовое поле формы, которое отправляется перед файлом.
+type formField struct {
+	Name  string
+	Value string
+}
+
+// multipartFileBody возвращает bodySource, который формирует multipart/form-data
+// с одним файлом потоково через io.Pipe: файл не буферизуется в памяти целиком.
+// Если размер артефакта известен, длина тела вычисляется заранее как размер файла
+// плюс служебные заголовки формы.
+func multipartFileBody(ctx context.Context, boundary, assetKey string, artifact Artifact, fields ...formField) bodySource {
+	return func() (io.ReadCloser, int64, error) {
+		overhead, err := multipartOverhead(boundary, assetKey, artifact.Name(), fields...)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		file, err := artifact.Open(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		file = countedBody(ctx, file)
+
+		pr, pw := io.Pipe()
+		go func() {
+			defer file.Close()
+			pw.CloseWithError(writeMultipartFile(pw, boundary, assetKey, artifact.Name(), file, fields...))
+		}()
+
+		if artifact.Size < 0 {
+			return pr, -1, nil
+		}
+		return pr, overhead + artifact.Size, nil
+	}
+}
+
+// writeMultipartFile записывает в w форму с полями fields и одним файлом.
+func writeMultipartFile(w io.Writer, boundary, assetKey, fileName string, file io.Reader, fields ...formField) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return fmt.Errorf("failed to set multipart boundary: %w", err)
+	}
+	for _, field := range fields {
+		if err := writer.WriteField(field.Name, field.Value); err != nil {
+			return fmt.Errorf("failed to write form field %s: %w", field.Name, err)
+		}
+	}
 
 	part, err := writer.CreateFormFile(assetKey, fileName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create form file: %w", err)
+		return fmt.Errorf("failed to create form file: %w", err)
 	}
 	if _, err = io.Copy(part, file); err != nil {
-		return nil, fmt.Errorf("failed to copy file to form: %w", err)
+		return fmt.Errorf("failed to copy file to form: %w", err)
 	}
 	if err = writer.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+		return fmt.Errorf("failed to close multipart writer: %w", err)
 	}
+	return nil
+}
+
+// multipartOverhead считает размер формы без содержимого файла:
+// заголовки части и закрывающий boundary.
+func multipartOverhead(boundary, assetKey, fileName string, fields ...formField) (int64, error) {
+	counter := &countingWriter{}
+	if err := writeMultipartFile(counter, boundary, assetKey, fileName, strings.NewReader(""), fields...); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// countingWriter считает записанные байты, ничего не сохраняя.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
 
-	resp, err := executeNexusRequest("POST", apiURL, writer.FormDataContentType(), body, username, password)
+// executeMultipartUpload создает и выполняет multipart/form-data запрос.
+func executeMultipartUpload(ctx context.Context, apiURL, assetKey string, artifact Artifact, auth Auth, fields ...formField) (*http.Response, error) {
+	// Boundary фиксируем заранее: он входит в Content-Type и должен совпадать во всех попытках.
+	boundaryWriter := multipart.NewWriter(io.Discard)
+	boundary, contentType := boundaryWriter.Boundary(), boundaryWriter.FormDataContentType()
+
+	body := multipartFileBody(ctx, boundary, assetKey, artifact, fields...)
+	resp, err := executeNexusRequest(ctx, "POST", apiURL, contentType, body, auth)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute multipart request: %w", err)
 	}
@@ -289,28 +331,82 @@ func executeMultipartUpload(apiURL, assetKey, fileName string, file io.Reader, u
 	return resp, nil
 }
 
-func executeNexusRequest(method, url, contentType string, body io.Reader, username, password string) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// executeNexusRequest выполняет запрос к Nexus, повторяя его по retryPolicy
+// после временных сетевых ошибок и статусов 408/429/502/503/504. Если попытки
+// кончились на временном статусе, возвращается последний ответ сервера.
+func executeNexusRequest(ctx context.Context, method, url, contentType string, body bodySource, auth Auth) (*http.Response, error) {
+	var resp *http.Response
+	err := retryPolicy.Do(ctx, func() error {
+		if resp != nil {
+			resp.Body.Close()
+			resp = nil
+		}
+
+		r, err := sendNexusRequest(ctx, method, url, contentType, body, auth)
+		if err != nil {
+			return err
+		}
+		resp = r
+		if isRetryableStatus(r.StatusCode) {
+			return retryableStatusError(r)
+		}
+		return nil
+	})
+	if resp != nil {
+		return resp, nil
 	}
+	return nil, err
+}
 
-	req, err := http.NewRequest(method, url, body)
+// sendNexusRequest выполняет одну попытку запроса. Временные сетевые ошибки
+// оборачиваются в retryableError.
+func sendNexusRequest(ctx context.Context, method, url, contentType string, body bodySource, auth Auth) (*http.Response, error) {
+	var reqBody io.ReadCloser
+	contentLength := int64(-1)
+	if body != nil {
+		var err error
+		reqBody, contentLength, err = body()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
+		if reqBody != nil {
+			reqBody.Close()
+		}
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if reqBody != nil {
+		switch {
+		case contentLength > 0:
+			req.ContentLength = contentLength
+		case contentLength == 0:
+			// Нулевая длина с непустым Body означает для net/http "неизвестно".
+			reqBody.Close()
+			req.Body = http.NoBody
+		}
+	}
 
-	req.Header.Set("Content-Type", contentType)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
-	if username != "" && password != "" {
-		auth := username + ":" + password
-		authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
-		req.Header.Set("Authorization", authHeader)
+	if auth != nil {
+		auth.Apply(req)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
+		requestStatsFrom(ctx).observe(method, url, 0)
+		err = fmt.Errorf("failed to execute request: %w", err)
+		if ctx.Err() == nil && isRetryableNetError(err) {
+			return nil, &retryableError{err: err}
+		}
+		return nil, err
+	}
+	requestStatsFrom(ctx).observe(method, url, resp.StatusCode)
 
 	return resp, nil
 }
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
+// mustLocalArtifact описывает локальный файл для загрузки или прерывает тест.
+func mustLocalArtifact(t *testing.T, filePath, importDir string) Artifact {
+	t.Helper()
+	artifact, err := localArtifact(filePath, importDir)
+	if err != nil {
+		t.Fatalf("localArtifact failed: %v", err)
+	}
+	return artifact
+}
+
 func TestUploadFileRaw(t *testing.T) {
 	// 1. Настраиваем тестовый сервер, который будет имитировать Nexus
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +57,7 @@ func TestUploadFileRaw(t *testing.T) {
 	}
 
 	// 3. Вызываем нашу функцию и проверяем результат
-	err := uploadFileRaw(server.URL, "test-raw", filePath, importDir, "", "", false)
+	err := uploadFileRaw(context.Background(), server.URL, "test-raw", mustLocalArtifact(t, filePath, importDir), nil, false)
 	if err != nil {
 		t.Errorf("uploadFileRaw failed: %v", err)
 	}
@@ -50,15 +65,14 @@ func TestUploadFileRaw(t *testing.T) {
 
 func TestUploadFileMaven(t *testing.T) {
 	// 1. Настраиваем тестовый сервер
+	// Кроме самого jar загружаются сгенерированные контрольные суммы
+	uploaded := make(map[string]string)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Проверяем, что URL для Maven был сформирован правильно
-		expectedPath := "/repository/test-maven/com/example/my-app/1.0/my-app-1.0.jar"
-		if r.URL.Path != expectedPath {
-			t.Errorf("Expected path %s, got %s", expectedPath, r.URL.Path)
-		}
 		if r.Method != http.MethodPut {
 			t.Errorf("Expected method PUT, got %s", r.Method)
 		}
+		body, _ := io.ReadAll(r.Body)
+		uploaded[r.URL.Path] = string(body)
 		w.WriteHeader(http.StatusCreated)
 	}))
 	defer server.Close()
@@ -76,8 +90,103 @@ func TestUploadFileMaven(t *testing.T) {
 	}
 
 	// 3. Вызываем функцию и проверяем результат
-	err := uploadFileMaven(server.URL, "test-maven", filePath, importDir, "", "", false)
+	err := uploadFileMaven(context.Background(), server.URL, "test-maven", mustLocalArtifact(t, filePath, importDir), nil, false)
 	if err != nil {
 		t.Errorf("uploadFileMaven failed: %v", err)
 	}
+	expectedPath := "/repository/test-maven/com/example/my-app/1.0/my-app-1.0.jar"
+	if uploaded[expectedPath] != "dummy jar content" {
+		t.Errorf("Expected jar at %s, got %v", expectedPath, uploaded)
+	}
+	if sum := uploaded[expectedPath+".sha1"]; sum != "7f3e3769e3b602316469ea5679de48bea7043765" {
+		t.Errorf("Expected generated sha1, got %q", sum)
+	}
+	if uploaded[expectedPath+".md5"] != "2136c226d87948d04acbef42d2de84a9" {
+		t.Errorf("Expected generated md5, got %q", uploaded[expectedPath+".md5"])
+	}
+}
+
+func TestExecuteMultipartUpload(t *testing.T) {
+	// 1. Сервер разбирает форму и проверяет длину тела и содержимое файла
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength <= 0 {
+			t.Errorf("Expected known Content-Length, got %d", r.ContentLength)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Failed to read request body: %v", err)
+		}
+		if int64(len(body)) != r.ContentLength {
+			t.Errorf("Content-Length %d does not match body size %d", r.ContentLength, len(body))
+		}
+
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatalf("Failed to parse Content-Type: %v", err)
+		}
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("Failed to read multipart part: %v", err)
+		}
+		if part.FormName() != "raw.asset" || part.FileName() != "file.txt" {
+			t.Errorf("Unexpected part %q with file name %q", part.FormName(), part.FileName())
+		}
+		content, _ := io.ReadAll(part)
+		if string(content) != "hello world" {
+			t.Errorf("Expected content 'hello world', got '%s'", string(content))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(filePath, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	resp, err := executeMultipartUpload(context.Background(), server.URL, "raw.asset", mustLocalArtifact(t, filePath, filepath.Dir(filePath)), nil)
+	if err != nil {
+		t.Fatalf("executeMultipartUpload failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestExecuteMultipartUploadMemory(t *testing.T) {
+	// 1. Сервер просто вычитывает тело, ничего не сохраняя
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// 2. Для файлов разного размера объем выделенной памяти должен оставаться одинаково малым
+	const limit = 16 << 20
+	for _, size := range []int64{32 << 20, 128 << 20} {
+		filePath := filepath.Join(t.TempDir(), "big.bin")
+		file, err := os.Create(filePath)
+		if err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		// Разреженный файл нужного размера создается мгновенно и не занимает место на диске.
+		if err := file.Truncate(size); err != nil {
+			t.Fatalf("Failed to resize test file: %v", err)
+		}
+		file.Close()
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		resp, err := executeMultipartUpload(context.Background(), server.URL, "raw.asset", mustLocalArtifact(t, filePath, filepath.Dir(filePath)), nil)
+		if err != nil {
+			t.Fatalf("executeMultipartUpload failed: %v", err)
+		}
+		resp.Body.Close()
+
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > limit {
+			t.Errorf("Uploading %d MB allocated %d MB, expected under %d MB", size>>20, allocated>>20, limit>>20)
+		}
+	}
 }
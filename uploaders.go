@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
 	"strings"
+	"sync"
 )
 
 // Uploader определяет контракт для загрузчиков разных форматов.
 type Uploader interface {
-	// Upload выполняет загрузку файла.
-	Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error
+	// Upload выполняет загрузку артефакта.
+	Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error
 	// IsSupported проверяет, подходит ли файл для данного загрузчика.
 	IsSupported(filePath string) bool
 }
 
+// metadataImporter — загрузчик, который после загрузки файлов восстанавливает
+// метаданные формата, сохраненные экспортом рядом с ними (например, dist-tags npm).
+type metadataImporter interface {
+	// ImportMetadata возвращает число обработанных документов и те из них, которые восстановить не удалось.
+	// Документы, уже восстановленные прерванным запуском (snapshot), пропускаются, а итог остальных пишется в journal.
+	// Ошибка возвращается, только если шаг прерван отменой ctx.
+	ImportMetadata(ctx context.Context, opts ImportOptions, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error)
+}
+
 // GetUploader возвращает нужную реализацию загрузчика по типу репозитория.
 func GetUploader(repoType string) (Uploader, bool) {
 	uploader, ok := uploaders[repoType]
@@ -31,67 +42,158 @@ var uploaders = map[string]Uploader{
 	"apt":   &AptUploader{},
 }
 
-type MavenUploader struct{}
+type MavenUploader struct {
+	// Components — загружать через components API: Nexus сам пересобирает
+	// maven-metadata.xml и контрольные суммы, поэтому эти файлы не загружаются.
+	Components bool
+}
 
-func (u *MavenUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileMaven(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *MavenUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	if u.Components {
+		return uploadFileMavenComponent(ctx, repoURL, repoName, artifact, auth, dryRun)
+	}
+	return uploadFileMaven(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *MavenUploader) IsSupported(filePath string) bool {
-	return strings.HasSuffix(filePath, ".jar") || strings.HasSuffix(filePath, ".pom")
+	// Любой файл раскладки Maven: классификаторы, подписи, .module, контрольные суммы,
+	// а вне раскладки — артефакты, координаты которых берутся из POM.
+	if u.Components && (isMavenMetadata(filePath) || isMavenChecksum(filePath)) {
+		return false
+	}
+	return isMavenLayoutFile(filePath) || isMavenArtifactFile(filePath)
+}
+func (u *MavenUploader) RepositoryPath(artifact Artifact) (string, error) {
+	return mavenRepositoryPath(artifact)
 }
 
 type NpmUploader struct{}
 
-func (u *NpmUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileNpm(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *NpmUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFileNpm(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *NpmUploader) IsSupported(filePath string) bool {
 	return strings.HasSuffix(filePath, ".tgz")
 }
+func (u *NpmUploader) Inspect(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	return readNpmPackage(ctx, artifact)
+}
 
 type RawUploader struct{}
 
-func (u *RawUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileRaw(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *RawUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFileRaw(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *RawUploader) IsSupported(filePath string) bool {
 	// Raw поддерживает любые файлы
 	return true
 }
+func (u *RawUploader) RepositoryPath(artifact Artifact) (string, error) {
+	return artifact.Path, nil
+}
 
 type PypiUploader struct{}
 
-func (u *PypiUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFilePypi(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *PypiUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFilePypi(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *PypiUploader) IsSupported(filePath string) bool {
-	return strings.HasSuffix(filePath, ".whl") || strings.HasSuffix(filePath, ".tar.gz")
+	return isPypiDistribution(filePath)
+}
+func (u *PypiUploader) Inspect(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	return readPypiDistribution(ctx, artifact)
+}
+
+type NugetUploader struct {
+	// APIKey — ключ для заголовка X-NuGet-ApiKey, если репозиторий требует его при публикации.
+	APIKey string
+
+	mu              sync.Mutex
+	symbolEndpoints map[string]string // адрес публикации символов по URL репозитория
+}
+
+func (u *NugetUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	if u.APIKey != "" {
+		auth = nugetAPIKeyAuth{Auth: auth, APIKey: u.APIKey}
+	}
+	if !isNugetSymbols(artifact.Path) {
+		return uploadFileNuget(ctx, repoURL, repoName, artifact, auth, dryRun)
+	}
+
+	// Пакеты символов публикуются не в корень репозитория, а в отдельную службу из индекса V3.
+	// Индекс только читается, поэтому проверяется и в режиме dry-run.
+	endpoint, err := u.symbolEndpoint(ctx, repoURL, repoName, auth)
+	if err != nil {
+		return err
+	}
+	if endpoint == "" {
+		return notPackage("repository %s does not accept symbol packages: no %s resource in its service index", repoName, strings.TrimSuffix(nugetSymbolPublishType, "/"))
+	}
+	if dryRun {
+		return nil
+	}
+	return pushNugetPackage(ctx, endpoint, artifact, auth)
+}
+
+// symbolEndpoint читает адрес публикации символов один раз на репозиторий.
+func (u *NugetUploader) symbolEndpoint(ctx context.Context, repoURL, repoName string, auth Auth) (string, error) {
+	key := repoURL + "/repository/" + repoName
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if endpoint, ok := u.symbolEndpoints[key]; ok {
+		return endpoint, nil
+	}
+	endpoint, err := nugetSymbolEndpoint(ctx, repoURL, repoName, auth)
+	if err != nil {
+		return "", err
+	}
+	if u.symbolEndpoints == nil {
+		u.symbolEndpoints = map[string]string{}
+	}
+	u.symbolEndpoints[key] = endpoint
+	return endpoint, nil
+}
+func (u *NugetUploader) IsSupported(filePath string) bool {
+	return strings.HasSuffix(filePath, ".nupkg") || strings.HasSuffix(filePath, ".snupkg")
+}
+func (u *NugetUploader) Inspect(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	if isNugetLegacySymbols(artifact.Path) {
+		return packageInfo{}, notPackage("legacy .symbols.nupkg symbol packages are not supported, use .snupkg")
+	}
+	return readNugetPackage(ctx, artifact)
+}
+func (u *NugetUploader) PackageKey(artifact Artifact, info packageInfo) string {
+	return strings.ToLower(info.Name) + "@" + nugetNormalizeVersion(info.Version)
 }
 
-type NugetUploader struct{}
-
-func (u *NugetUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileNuget(repoURL, repoName, filePath, importDir, username, password, dryRun)
+// IsCompanion сообщает, что файл — пакет символов: он загружается после основного пакета.
+func (u *NugetUploader) IsCompanion(filePath string) bool {
+	return isNugetSymbols(filePath)
 }
-func (u *NugetUploader) IsSupported(filePath string) bool {
-	return strings.HasSuffix(filePath, ".nupkg")
+
+// MainPath возвращает путь пакета, который nuget pack кладет рядом с пакетом символов.
+func (u *NugetUploader) MainPath(filePath string) string {
+	return strings.TrimSuffix(filePath, ".snupkg") + ".nupkg"
 }
 
 type HelmUploader struct{}
 
-func (u *HelmUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileHelm(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *HelmUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFileHelm(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *HelmUploader) IsSupported(filePath string) bool {
-	// Helm чарты и npm пакеты имеют одинаковое расширение.
-	// Здесь мы доверяем флагу -repo-type, который указал пользователь.
+	// Helm чарты и npm пакеты имеют одинаковое расширение,
+	// поэтому перед загрузкой Inspect проверяет Chart.yaml.
 	return strings.HasSuffix(filePath, ".tgz")
 }
+func (u *HelmUploader) Inspect(ctx context.Context, artifact Artifact) (packageInfo, error) {
+	chart, err := readHelmChart(ctx, artifact)
+	return packageInfo{Name: chart.Name, Version: chart.Version}, err
+}
 
 type YumUploader struct{}
 
-func (u *YumUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileYum(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *YumUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFileYum(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *YumUploader) IsSupported(filePath string) bool {
 	return strings.HasSuffix(filePath, ".rpm")
@@ -99,8 +201,8 @@ func (u *YumUploader) IsSupported(filePath string) bool {
 
 type AptUploader struct{}
 
-func (u *AptUploader) Upload(repoURL, repoName, filePath, importDir, username, password string, dryRun bool) error {
-	return uploadFileApt(repoURL, repoName, filePath, importDir, username, password, dryRun)
+func (u *AptUploader) Upload(ctx context.Context, repoURL, repoName string, artifact Artifact, auth Auth, dryRun bool) error {
+	return uploadFileApt(ctx, repoURL, repoName, artifact, auth, dryRun)
 }
 func (u *AptUploader) IsSupported(filePath string) bool {
 	return strings.HasSuffix(filePath, ".deb")
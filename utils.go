@@ -1,188 +1,595 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 )
 
 type downloadTask struct {
-	URL      string
-	FilePath string
+	URL          string            `json:"url"`
+	FilePath     string            `json:"filePath"`
+	Checksum     map[string]string `json:"checksum,omitempty"`
+	Size         int64             `json:"size,omitempty"`
+	LastModified time.Time         `json:"lastModified,omitempty"`
+}
+
+// assetTask описывает скачивание ассета в filePath.
+func assetTask(asset Asset, filePath string) downloadTask {
+	return downloadTask{
+		URL:          asset.DownloadURL,
+		FilePath:     filePath,
+		Checksum:     asset.Checksum,
+		Size:         asset.FileSize,
+		LastModified: asset.LastModified,
+	}
+}
+
+type exportResult struct {
+	Status exportStatus
+	Err    error
+}
+
+// importResult — итог импорта одного файла: "uploaded", importPresent или "unsupported".
+type importResult struct {
+	Path    string
+	Outcome string
+	Package string // name@version, если загрузчик прочитал пакет из файла
+	Reason  string // почему файл пропущен как "unsupported"
+	Err     error
 }
 
 type uploadTask struct {
-	FilePath string
+	FilePath string `json:"filePath"`
 }
 
-func ExportFiles(repoURL, repoName, repoType string, dryRun bool, numWorkers int) error {
-	exportDir := repoName
-	err := os.MkdirAll(exportDir, 0755) // Более безопасные права доступа
+// defaultJournalPath возвращает путь журнала по умолчанию в текущей директории.
+// Журнал не кладется в директорию экспорта, чтобы не попасть в последующий импорт.
+func defaultJournalPath(action, repoName string) string {
+	return fmt.Sprintf("nexus-operator-%s-%s.journal.jsonl", action, repoName)
+}
+
+// journalWarn сообщает об ошибке записи журнала, не прерывая перенос файлов.
+func journalWarn(err error) {
 	if err != nil {
-		return fmt.Errorf("failed to create export directory: %w", err)
+		fmt.Fprintf(os.Stderr, "Ошибка записи журнала: %v\n", err)
 	}
+}
 
-	var allAssets []Asset
-	continuationToken := ""
+// ExportOptions задает параметры экспорта репозитория.
+type ExportOptions struct {
+	RepoURL     string
+	RepoName    string
+	RepoType    string
+	Auth        Auth
+	DryRun      bool
+	Incremental bool // пропускать файлы, которые уже есть локально и не изменились
+	SimpleIndex bool // pypi: построить в директории экспорта индекс PEP 503 simple/
+	Workers     int
+	QueueSize   int // сколько ассетов может ждать скачивания между листингом и воркерами
+
+	Journal string // путь к журналу; пусто — путь по умолчанию
+	Resume  bool   // продолжить прерванный запуск по журналу
+
+	Report *Report // отчет по файлам; nil — не вести
+}
 
-	for {
-		searchResult, err := fetchAssets(repoURL, repoName, continuationToken)
-		if err != nil {
-			return fmt.Errorf("error fetching assets: %w", err)
-		}
+// ExportFiles скачивает все ассеты репозитория в директорию с его именем.
+// Страницы поиска передаются воркерам по мере получения, поэтому скачивание
+// начинается сразу, а память ограничена размером очереди.
+// При отмене ctx новые скачивания не начинаются, текущие прерываются,
+// а их временные файлы удаляются. Состояние задач пишется в журнал, по которому
+// запуск с Resume продолжает работу без повторного листинга уже прочитанных страниц.
+func ExportFiles(ctx context.Context, opts ExportOptions) error {
+	exportDir := opts.RepoName
+	err := os.MkdirAll(exportDir, 0755) // Более безопасные права доступа
+	if err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if err := removePartialDownloads(exportDir, opts.DryRun); err != nil {
+		return fmt.Errorf("failed to clean up partial downloads: %w", err)
+	}
 
-		allAssets = append(allAssets, searchResult.Items...)
+	exporter := GetExporter(opts.RepoType)
+	run, err := runAssetPipeline(ctx, assetPipeline{
+		Run:       journalRun{Action: "export", RepoURL: opts.RepoURL, RepoName: opts.RepoName},
+		Journal:   opts.Journal,
+		Resume:    opts.Resume,
+		DryRun:    opts.DryRun,
+		Workers:   opts.Workers,
+		QueueSize: opts.QueueSize,
+		Report:    opts.Report,
+		Feed: assetFeed{
+			RepoURL:  opts.RepoURL,
+			RepoName: opts.RepoName,
+			Auth:     opts.Auth,
+			NewTask: func(asset Asset) downloadTask {
+				return assetTask(asset, filepath.Join(exportDir, exporter.GetLocalPath(asset.Path)))
+			},
+		},
+		Process: func(ctx context.Context, task downloadTask) (string, error) {
+			result := exportAsset(ctx, task, opts.Auth, opts.DryRun, opts.Incremental)
+			return result.Status.String(), result.Err
+		},
+		Metadata:    exportMetadata(exporter, opts, exportDir),
+		Title:       "Exporting",
+		Noun:        "Экспорт",
+		Done:        "скачано",
+		Failure:     "Ошибка скачивания",
+		Interrupted: "export interrupted",
+	})
+	if err != nil || run.Empty() {
+		return err
+	}
 
-		if searchResult.ContinuationToken == "" {
-			break
-		}
+	total, failed := run.Total+run.Metadata, run.Failed
+	failedCount := len(failed)
+	skippedCount := run.Outcomes[exportSkipped.String()]
 
-		continuationToken = searchResult.ContinuationToken
+	if opts.DryRun {
+		fmt.Printf("[Dry Run] Было бы предпринято %d скачиваний.\n", total-skippedCount)
+	} else {
+		fmt.Printf("Всего обработано файлов: %d, успешно: %d, с ошибками: %d\n", total, total-failedCount, failedCount)
+	}
+	if opts.Incremental {
+		fmt.Printf("Новых: %d, обновлено: %d, пропущено без изменений: %d\n",
+			run.Outcomes[exportNew.String()], run.Outcomes[exportUpdated.String()], skippedCount)
 	}
 
-	if len(allAssets) == 0 {
-		fmt.Println("No assets found in the repository.")
+	return partialFailure("файлов не удалось скачать", total, failed)
+}
+
+// exportMetadata возвращает шаг метаданных конвейера экспорта или nil,
+// если экспортер формата метаданных не сохраняет.
+func exportMetadata(exporter Exporter, opts ExportOptions, exportDir string) func(context.Context, *Journal, *journalSnapshot) (int, []FailedItem, error) {
+	metadata, ok := exporter.(metadataExporter)
+	if !ok {
 		return nil
 	}
+	return func(ctx context.Context, journal *Journal, snapshot *journalSnapshot) (int, []FailedItem, error) {
+		return metadata.ExportMetadata(ctx, opts, exportDir, journal, snapshot)
+	}
+}
 
-	var wg sync.WaitGroup
-	total := len(allAssets)
-	bar := progressbar.NewOptions(total,
-		progressbar.OptionSetDescription("Exporting"),
-		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
-	)
+// assetFeed наполняет очередь задач ассетами репозитория: сначала невыполненными
+// задачами из журнала прерванного запуска, затем ассетами со страниц листинга
+// по мере их получения. Каждая новая задача записывается в журнал до постановки в очередь.
+type assetFeed struct {
+	RepoURL  string
+	RepoName string
+	Auth     Auth
+	Journal  *Journal
+	Snapshot *journalSnapshot               // состояние прерванного запуска или nil
+	NewTask  func(asset Asset) downloadTask // превращает ассет в задачу
+	Grow     func(n int)                    // вызывается, когда в очередь добавляется n задач
+}
 
-	// --- Worker Pool для скачивания ---
-	tasks := make(chan downloadTask, total)
-	results := make(chan error, total)
-
-	wg.Add(numWorkers)
-	for w := 1; w <= numWorkers; w++ {
-		go func() {
-			defer wg.Done()
-			for task := range tasks {
-				err := downloadFile(task.URL, task.FilePath, dryRun)
-				results <- err
-				bar.Add(1)
-			}
-		}()
+// Run заполняет tasks и возвращает число задач, выполненных еще в прерванном запуске.
+func (f assetFeed) Run(ctx context.Context, tasks chan<- downloadTask) (resumedDone int, err error) {
+	enqueue := func(task downloadTask) error {
+		select {
+		case tasks <- task:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
-	exporter := GetExporter(repoType)
-	for _, asset := range allAssets {
-		relativePath := exporter.GetLocalPath(asset.Path)
-		filePath := filepath.Join(exportDir, relativePath)
-		tasks <- downloadTask{URL: asset.DownloadURL, FilePath: filePath}
+	// При продолжении сначала возвращаем в очередь невыполненные задачи из журнала.
+	startToken := ""
+	if f.Snapshot != nil {
+		pending := f.Snapshot.Pending()
+		resumedDone = len(f.Snapshot.Order) - len(pending)
+		f.Grow(len(pending))
+		for _, entry := range pending {
+			var task downloadTask
+			if err := json.Unmarshal(entry.Task, &task); err != nil {
+				return resumedDone, fmt.Errorf("corrupted journal entry for %s: %w", entry.Key, err)
+			}
+			if err := enqueue(task); err != nil {
+				return resumedDone, err
+			}
+		}
+		if f.Snapshot.Listed {
+			return resumedDone, nil
+		}
+		startToken = f.Snapshot.NextToken
 	}
-	close(tasks)
 
-	wg.Wait()
-	close(results)
+	err = walkAssets(ctx, f.RepoURL, f.RepoName, startToken, f.Auth, func(items []Asset, nextToken string) error {
+		for _, asset := range items {
+			task := f.NewTask(asset)
+			// Страница могла быть частично записана до падения: такие задачи уже в очереди.
+			if f.Snapshot != nil && f.Snapshot.Known(task.FilePath) {
+				continue
+			}
+			if err := f.Journal.AddTask(task.FilePath, task); err != nil {
+				return err
+			}
+			f.Grow(1)
+			if err := enqueue(task); err != nil {
+				return err
+			}
+		}
+		if nextToken == "" {
+			return f.Journal.Listed()
+		}
+		return f.Journal.Page(nextToken)
+	})
+	return resumedDone, err
+}
 
-	failedCount := 0
-	for err := range results {
+// exportAsset скачивает один ассет. В режиме incremental сначала сравнивает его
+// с локальным файлом и пропускает скачивание, если файл не изменился.
+func exportAsset(ctx context.Context, task downloadTask, auth Auth, dryRun, incremental bool) exportResult {
+	status := exportNew
+	if incremental {
+		var err error
+		status, err = localFileStatus(task)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Ошибка скачивания: %v\n", err)
-			failedCount++
+			return exportResult{Err: fmt.Errorf("%s: %w", task.FilePath, err)}
+		}
+		if status == exportSkipped {
+			return exportResult{Status: status}
 		}
 	}
 
-	if dryRun {
-		fmt.Printf("[Dry Run] Было бы предпринято %d скачиваний.\n", len(allAssets))
-	} else {
-		fmt.Printf("Всего обработано файлов: %d, успешно: %d, с ошибками: %d\n", len(allAssets), len(allAssets)-failedCount, failedCount)
+	if err := downloadFile(ctx, task.URL, task.FilePath, task.Checksum, auth, dryRun); err != nil {
+		return exportResult{Status: status, Err: err}
 	}
 
-	if failedCount > 0 {
-		return fmt.Errorf("%d файлов не удалось скачать", failedCount)
+	// Сохраняем дату изменения из Nexus, чтобы следующий инкрементальный запуск мог на нее опереться.
+	if !dryRun && !task.LastModified.IsZero() {
+		os.Chtimes(task.FilePath, task.LastModified, task.LastModified)
 	}
+	return exportResult{Status: status}
+}
 
-	return nil
+// ImportOptions задает параметры импорта в репозиторий.
+type ImportOptions struct {
+	RepoURL   string
+	RepoName  string
+	RepoType  string
+	ImportDir string
+	Auth      Auth
+	DryRun    bool
+	Workers   int
+
+	// OnConflict — что делать с файлами, которые уже есть в репозитории:
+	// conflictSkip, conflictOverwrite (по умолчанию) или conflictFail.
+	OnConflict string
+
+	// MavenComponents — загружать Maven через components API, чтобы Nexus пересобрал maven-metadata.xml.
+	MavenComponents bool
+
+	// NugetAPIKey — ключ для заголовка X-NuGet-ApiKey при публикации пакетов NuGet.
+	NugetAPIKey string
+
+	Journal string // путь к журналу; пусто — путь по умолчанию
+	Resume  bool   // продолжить прерванный запуск по журналу
+
+	Report *Report // отчет по файлам; nil — не вести
 }
 
-func ImportFiles(repoURL, repoName, importDir, repoType, username, password string, dryRun bool, numWorkers int) error {
+// ImportFiles загружает поддерживаемые файлы из ImportDir в репозиторий.
+// При отмене ctx новые загрузки не начинаются, а текущие прерываются.
+// Состояние задач пишется в журнал, по которому запуск с Resume загружает
+// только файлы, не загруженные ранее, не обходя директорию повторно.
+func ImportFiles(ctx context.Context, opts ImportOptions) error {
 	var filesToUpload []string
 
-	uploader, ok := GetUploader(repoType)
+	uploader, ok := GetUploader(opts.RepoType)
 	if !ok {
-		return fmt.Errorf("неподдерживаемый тип репозитория: %s", repoType)
+		return fmt.Errorf("неподдерживаемый тип репозитория: %s", opts.RepoType)
+	}
+	if opts.MavenComponents && opts.RepoType == "maven" {
+		uploader = &MavenUploader{Components: true}
+	}
+	if opts.NugetAPIKey != "" && opts.RepoType == "nuget" {
+		uploader = &NugetUploader{APIKey: opts.NugetAPIKey}
+	}
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = conflictOverwrite
+	case conflictSkip, conflictOverwrite, conflictFail:
+	default:
+		return fmt.Errorf("unknown conflict policy %q, use '%s', '%s' or '%s'", opts.OnConflict, conflictSkip, conflictOverwrite, conflictFail)
 	}
 
-	// 1. Собираем все файлы для загрузки
-	err := filepath.Walk(importDir, func(path string, info os.FileInfo, err error) error {
+	var journal *Journal
+	var snapshot *journalSnapshot
+	if !opts.DryRun {
+		journalPath := opts.Journal
+		if journalPath == "" {
+			journalPath = defaultJournalPath("import", opts.RepoName)
+		}
+		run := journalRun{Action: "import", RepoURL: opts.RepoURL, RepoName: opts.RepoName, Source: opts.ImportDir}
+		var err error
+		journal, snapshot, err = openJournal(journalPath, run, opts.Resume)
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			return nil
+	}
+
+	// 1. Собираем все файлы для загрузки: из журнала, если обход уже был завершен, иначе обходом директории
+	resumedDone := 0
+	if snapshot != nil && snapshot.Listed {
+		pending := snapshot.Pending()
+		resumedDone = len(snapshot.Order) - len(pending)
+		for _, entry := range pending {
+			filesToUpload = append(filesToUpload, entry.Key)
 		}
+	} else {
+		err := filepath.Walk(opts.ImportDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if info.IsDir() {
+				return nil
+			}
 
-		if uploader.IsSupported(path) {
+			if !uploader.IsSupported(path) {
+				return nil
+			}
+			if snapshot != nil && snapshot.Done(path) {
+				resumedDone++
+				return nil
+			}
+			if snapshot == nil || !snapshot.Known(path) {
+				if err := journal.AddTask(path, uploadTask{FilePath: path}); err != nil {
+					return err
+				}
+			}
 			filesToUpload = append(filesToUpload, path)
+			return nil
+		})
+
+		if err != nil {
+			journal.Close(false)
+			return fmt.Errorf("ошибка при обходе директории импорта: %w", err)
 		}
-		return nil
-	})
+		journalWarn(journal.Listed())
+	}
 
-	if err != nil {
-		return fmt.Errorf("ошибка при обходе директории импорта: %w", err)
+	if resumedDone > 0 {
+		fmt.Printf("Продолжение прерванного запуска: %d файлов уже было загружено ранее.\n", resumedDone)
 	}
 
 	if len(filesToUpload) == 0 {
 		fmt.Println("Не найдено файлов для загрузки.")
-		return nil
+		// Метаданные восстанавливаются и тогда, когда все файлы уже загружены:
+		// например, при -resume после загрузки тарболов, но до восстановления dist-tags.
+		return finishImport(ctx, uploader, opts, journal, snapshot, 0, nil)
+	}
+
+	// Файлы с одним и тем же пакетом находятся до загрузки: иначе результат зависел бы
+	// от того, какой из них загрузится последним. Загружается первый, остальные считаются ошибкой.
+	plan := packagePlan{Rejected: map[string]error{}}
+	if detector, ok := uploader.(duplicateDetector); ok {
+		plan = planPackages(ctx, detector, filesToUpload, opts.ImportDir)
+		if len(plan.Rejected) > 0 {
+			fmt.Fprintf(os.Stderr, "Найдено файлов с повторяющимися пакетами: %d\n", len(plan.Rejected))
+		}
+	}
+
+	// Дополнения к пакетам (пакеты символов NuGet) загружаются вторым проходом,
+	// когда известно, загружен ли их основной пакет.
+	mainFiles, companionFiles := filesToUpload, []string(nil)
+	if companions, ok := uploader.(companionUploader); ok {
+		mainFiles = nil
+		for _, filePath := range filesToUpload {
+			if companions.IsCompanion(filePath) {
+				companionFiles = append(companionFiles, filePath)
+			} else {
+				mainFiles = append(mainFiles, filePath)
+			}
+		}
 	}
 
-	var wg sync.WaitGroup
 	total := len(filesToUpload)
 	bar := progressbar.NewOptions(total,
 		progressbar.OptionSetDescription("Importing"),
 		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
 	)
 
-	// --- Worker Pool для загрузки ---
-	tasks := make(chan uploadTask, total)
-	results := make(chan error, total)
-
-	wg.Add(numWorkers)
-	for w := 1; w <= numWorkers; w++ {
-		go func() {
-			defer wg.Done()
-			for task := range tasks {
-				uploadErr := uploader.Upload(repoURL, repoName, task.FilePath, importDir, username, password, dryRun)
-				results <- uploadErr
-				bar.Add(1)
-			}
-		}()
+	// С -on-conflict=fail первый конфликт останавливает импорт так же, как отмена,
+	// но запуск завершается ошибкой конфликта, а не прерыванием.
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	var conflictOnce sync.Once
+	var conflictErr error
+
+	results := make(chan importResult, total)
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
 	}
+	var uploadedMu sync.Mutex
+	uploaded := map[string]bool{}
 
-	for _, filePath := range filesToUpload {
-		tasks <- uploadTask{FilePath: filePath}
+	// --- Worker Pool для загрузки ---
+	uploadAll := func(files []string) {
+		tasks := make(chan uploadTask, len(files))
+		for _, filePath := range files {
+			tasks <- uploadTask{FilePath: filePath}
+		}
+		close(tasks)
+
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 1; w <= workers; w++ {
+			go func() {
+				defer wg.Done()
+				for task := range tasks {
+					if runCtx.Err() != nil {
+						continue // после отмены оставшиеся задачи считаются невыполненными
+					}
+					taskCtx, stats := trackRequests(runCtx)
+					started := time.Now()
+					var result importResult
+					if err, ok := plan.Rejected[task.FilePath]; ok {
+						result = importResult{Path: task.FilePath, Err: err}
+					} else {
+						result = importFile(taskCtx, uploader, task.FilePath, opts)
+					}
+					uploadErr := result.Err
+					if uploadErr != nil && runCtx.Err() != nil {
+						continue // загрузка прервана отменой, а не ошибкой
+					}
+					item := stats.item(task.FilePath, "", result.Outcome, started, uploadErr)
+					item.Package, item.Reason = result.Package, result.Reason
+					opts.Report.Add(item)
+					journalWarn(journal.Finish(task.FilePath, uploadErr))
+					if opts.OnConflict == conflictFail && errors.Is(uploadErr, ErrConflict) {
+						conflictOnce.Do(func() {
+							conflictErr = fmt.Errorf("%s: %w", task.FilePath, uploadErr)
+							stop()
+						})
+					}
+					if uploadErr == nil && result.Outcome != "unsupported" {
+						uploadedMu.Lock()
+						uploaded[task.FilePath] = true
+						uploadedMu.Unlock()
+					}
+					results <- result
+					bar.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
 	}
-	close(tasks)
 
-	wg.Wait()
+	uploadAll(mainFiles)
+	for _, filePath := range companionFiles {
+		if main, ok := plan.MainOf[filePath]; ok && !uploaded[main] {
+			if _, rejected := plan.Rejected[filePath]; !rejected {
+				plan.Rejected[filePath] = fmt.Errorf("main package %s was not uploaded", main)
+			}
+		}
+	}
+	uploadAll(companionFiles)
 	close(results)
 
-	failedCount := 0
-	for err := range results {
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Ошибка загрузки: %v\n", err)
-			failedCount++
+	processed, presentCount, unsupportedCount := 0, 0, 0
+	var failed []FailedItem
+	for result := range results {
+		processed++
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка загрузки: %v\n", result.Err)
+			failed = append(failed, FailedItem{Path: result.Path, Err: result.Err})
+			continue
+		}
+		switch result.Outcome {
+		case importPresent:
+			presentCount++
+		case "unsupported":
+			fmt.Fprintf(os.Stderr, "Пропущен %s: %s\n", result.Path, result.Reason)
+			unsupportedCount++
 		}
 	}
+	failedCount := len(failed)
 
-	if dryRun {
-		fmt.Printf("[Dry Run] Было бы предпринято %d загрузок.\n", len(filesToUpload))
+	if ctx.Err() != nil || conflictErr != nil {
+		journalWarn(journal.Close(false))
+	}
+	if ctx.Err() != nil {
+		fmt.Printf("Импорт прерван: завершено %d, не выполнено %d из %d файлов, с ошибками: %d\n",
+			processed-failedCount, total-processed, total, failedCount)
+		fmt.Println("Запустите импорт повторно с -resume, чтобы продолжить.")
+		return fmt.Errorf("import interrupted: %w", ctx.Err())
+	}
+	if conflictErr != nil {
+		fmt.Printf("Импорт остановлен на конфликте: завершено %d, не выполнено %d из %d файлов.\n",
+			processed-failedCount, total-processed, total)
+		return fmt.Errorf("import stopped (-on-conflict=%s): %w", conflictFail, conflictErr)
+	}
+
+	if opts.DryRun {
+		fmt.Printf("[Dry Run] Было бы предпринято %d загрузок.\n", len(filesToUpload)-presentCount-unsupportedCount)
 	} else {
 		fmt.Printf("Всего обработано файлов: %d, успешно: %d, с ошибками: %d\n", len(filesToUpload), len(filesToUpload)-failedCount, failedCount)
 	}
+	if opts.OnConflict == conflictSkip {
+		fmt.Printf("Уже есть в репозитории и пропущено: %d\n", presentCount)
+	}
+	if unsupportedCount > 0 {
+		fmt.Printf("Пропущено файлов, которые не являются пакетами формата %s: %d\n", opts.RepoType, unsupportedCount)
+	}
+
+	// Метаданные восстанавливаются после загрузки файлов, на которые они ссылаются.
+	return finishImport(ctx, uploader, opts, journal, snapshot, total, failed)
+}
 
-	if failedCount > 0 {
-		return fmt.Errorf("%d файлов не удалось загрузить", failedCount)
+// finishImport восстанавливает метаданные формата и закрывает журнал импорта.
+// Журнал удаляется, только если успешны и файлы, и метаданные: иначе -resume
+// после ошибки в метаданных загрузил бы все файлы заново.
+func finishImport(ctx context.Context, uploader Uploader, opts ImportOptions, journal *Journal, snapshot *journalSnapshot, total int, failed []FailedItem) error {
+	if metadata, ok := uploader.(metadataImporter); ok {
+		count, metadataFailed, err := metadata.ImportMetadata(ctx, opts, journal, snapshot)
+		if err != nil {
+			journalWarn(journal.Close(false))
+			fmt.Println("Импорт прерван при восстановлении метаданных.")
+			fmt.Println("Запустите импорт повторно с -resume, чтобы продолжить.")
+			return fmt.Errorf("import interrupted: %w", err)
+		}
+		total += count
+		failed = append(failed, metadataFailed...)
 	}
+	journalWarn(journal.Close(len(failed) == 0))
+	return partialFailure("файлов не удалось загрузить", total, failed)
+}
 
-	return nil
+// importFile загружает один локальный файл из директории импорта. Если загрузчик
+// умеет читать пакет из файла, файл, который не оказался пакетом, пропускается с причиной.
+func importFile(ctx context.Context, uploader Uploader, filePath string, opts ImportOptions) importResult {
+	result := importResult{Path: filePath, Outcome: "uploaded"}
+	artifact, err := localArtifact(filePath, opts.ImportDir)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if inspector, ok := uploader.(packageInspector); ok {
+		info, err := inspector.Inspect(ctx, artifact)
+		var skip *notPackageError
+		if errors.As(err, &skip) {
+			result.Outcome, result.Reason = "unsupported", skip.Reason
+			return result
+		}
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Package = info.String()
+	}
+	// Проверка наличия только читает репозиторий, поэтому выполняется и в режиме dry-run.
+	if opts.OnConflict == conflictSkip {
+		exists, err := artifactExists(ctx, opts.RepoURL, opts.RepoName, uploader, artifact, opts.Auth)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if exists {
+			result.Outcome = importPresent
+			return result
+		}
+	}
+	// Загрузчик может отказаться от файла и сам: например, когда репозиторий не принимает его вид.
+	err = uploader.Upload(ctx, opts.RepoURL, opts.RepoName, artifact, opts.Auth, opts.DryRun)
+	var skip *notPackageError
+	if errors.As(err, &skip) {
+		result.Outcome, result.Reason = "unsupported", skip.Reason
+		return result
+	}
+	result.Err = err
+	return result
 }